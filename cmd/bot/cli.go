@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"rail-go/internal/config"
+	"rail-go/internal/logger"
+	"rail-go/internal/train"
+)
+
+const usageText = `Usage: rail-go [command]
+
+Commands:
+  bot                       Run the Telegram bot (default)
+  schedule                  Show trains between two stations
+      --from <station>      Origin station name or ID
+      --to <station>        Destination station name or ID
+      --at <time>           Departure time: HH:MM, "YYYY-MM-DD HH:MM" or RFC 3339 (default now)
+      --limit <n>           Number of trips to show (default 5)
+  stations search <query>   Find stations by Hebrew or English name
+  config check              Load and validate the configuration
+      --print               Print the effective configuration (without secrets)
+
+Output flags (schedule, stations search):
+  -o, --output table|json   Output format (default table)
+`
+
+// run dispatches the command line and returns the exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		return runBot()
+	}
+
+	var err error
+	switch args[0] {
+	case "bot":
+		return runBot()
+	case "schedule":
+		err = runSchedule(args[1:], stdout)
+	case "stations":
+		err = runStations(args[1:], stdout)
+	case "config":
+		err = runConfig(args[1:], stdout)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usageText)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usageText)
+		return 2
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprint(stdout, usageText)
+		return 0
+	}
+	var usageErr usageError
+	if errors.As(err, &usageErr) {
+		fmt.Fprintf(stderr, "%v\n\n%s", err, usageText)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// usageError is a command line mistake, reported together with the usage text
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// newFlagSet returns a flag set that reports errors instead of exiting
+func newFlagSet(name string, output *string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if output != nil {
+		fs.StringVar(output, "output", "table", "output format: table or json")
+		fs.StringVar(output, "o", "table", "output format: table or json")
+	}
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usageError(fmt.Sprintf("%s: %v", fs.Name(), err))
+	}
+	return nil
+}
+
+// parseInterspersed parses flags placed anywhere among the arguments, so that
+// "stations search herzliya -o json" is read like "stations search -o json
+// herzliya", and returns the other arguments. Arguments after "--" are never
+// taken as flags.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := parseFlags(fs, args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if len(rest) < len(args) && args[len(args)-len(rest)-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+func checkOutput(output string) error {
+	if output != "table" && output != "json" {
+		return usageError(fmt.Sprintf("invalid output %q: must be table or json", output))
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func runSchedule(args []string, stdout io.Writer) error {
+	var from, to, at, output string
+	var limit int
+	fs := newFlagSet("schedule", &output)
+	fs.StringVar(&from, "from", "", "origin station")
+	fs.StringVar(&to, "to", "", "destination station")
+	fs.StringVar(&at, "at", "", "departure time")
+	fs.IntVar(&limit, "limit", 5, "number of trips")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := checkOutput(output); err != nil {
+		return err
+	}
+	if from == "" || to == "" {
+		return usageError("schedule: --from and --to are required")
+	}
+
+	when, err := train.ParseTime(at, time.Now())
+	if err != nil {
+		return usageError(fmt.Sprintf("schedule: %v", err))
+	}
+
+	trainService, err := newTrainService()
+	if err != nil {
+		return err
+	}
+
+	result, err := trainService.PlanTrips(context.Background(), from, to, when)
+	if err != nil {
+		return err
+	}
+	if limit > 0 && len(result.Trips) > limit {
+		result.Trips = result.Trips[:limit]
+	}
+	trips := result.Trips
+
+	if output == "json" {
+		return writeJSON(stdout, result)
+	}
+
+	fmt.Fprintf(stdout, "%s → %s\n", result.From.Eng, result.To.Eng)
+	if result.Notice != "" {
+		fmt.Fprintln(stdout, result.Notice)
+	}
+	if len(trips) == 0 {
+		fmt.Fprintln(stdout, "no trains found")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DEPART\tARRIVE\tDURATION\tCHANGES\tPLATFORM\tVIA")
+	for _, trip := range trips {
+		var via []string
+		for _, leg := range trip.Legs[1:] {
+			via = append(via, stationName(leg.From))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\n",
+			trip.Departure.Format("15:04"),
+			trip.Arrival.Format("15:04"),
+			trip.Arrival.Sub(trip.Departure).Round(time.Minute).String(),
+			trip.Changes(),
+			trip.Legs[0].FromPlatform,
+			strings.Join(via, ", "))
+	}
+	return tw.Flush()
+}
+
+func runStations(args []string, stdout io.Writer) error {
+	if len(args) == 0 || args[0] != "search" {
+		return usageError("stations: expected \"search <query>\"")
+	}
+
+	var output string
+	fs := newFlagSet("stations search", &output)
+	words, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if err := checkOutput(output); err != nil {
+		return err
+	}
+	query := strings.Join(words, " ")
+	if query == "" {
+		return usageError("stations search: a query is required")
+	}
+
+	if _, _, err := loadOffline(); err != nil {
+		return err
+	}
+
+	stations := train.FindStations(query)
+	if output == "json" {
+		if stations == nil {
+			stations = []train.Station{}
+		}
+		return writeJSON(stdout, stations)
+	}
+	if len(stations) == 0 {
+		return fmt.Errorf("no station matches %q", query)
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tENGLISH\tHEBREW")
+	for _, station := range stations {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", station.ID, station.Eng, station.Heb)
+	}
+	return tw.Flush()
+}
+
+func runConfig(args []string, stdout io.Writer) error {
+	if len(args) == 0 || args[0] != "check" {
+		return usageError("config: expected \"check\"")
+	}
+
+	var printConfig bool
+	fs := newFlagSet("config check", nil)
+	fs.BoolVar(&printConfig, "print", false, "print the effective configuration")
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	if printConfig {
+		encoder := yaml.NewEncoder(stdout)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(cfg); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+
+	fmt.Fprintf(stdout, "configuration is valid (%d notification jobs)\n", len(cfg.Notifications.Jobs))
+	return nil
+}
+
+func stationName(id string) string {
+	if station, ok := train.StationByID(id); ok {
+		return station.Eng
+	}
+	return id
+}
+
+// newTrainService builds a train service from the configuration
+func newTrainService() (*train.Service, error) {
+	cfg, log, err := loadOffline()
+	if err != nil {
+		return nil, err
+	}
+	return train.NewService(cfg, log), nil
+}
+
+// loadOffline loads the configuration and the stations file it names, like the
+// bot does, for the commands that run without Telegram. Logs go to stderr and
+// only warnings are shown so they do not mix with the output.
+func loadOffline() (*config.Config, *logger.Logger, error) {
+	cfg, err := config.LoadOffline()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	if cfg.Log.Level == "info" || cfg.Log.Level == "debug" {
+		cfg.Log.Level = "warn"
+	}
+	cfg.Log.Outputs = []string{"stderr"}
+
+	log, err := logger.NewFromConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger.SetDefault(log)
+
+	if cfg.Train.StationsFile != "" {
+		if _, err := train.LoadStations(cfg.Train.StationsFile); err != nil {
+			return nil, nil, err
+		}
+	}
+	return cfg, log, nil
+}
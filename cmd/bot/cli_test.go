@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rail-go/internal/train"
+)
+
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result": {"travels": [
+			{"trains": [{"orignStation": 3500, "destinationStation": 4600, "departureTime": "2025-05-04T08:05:00", "arrivalTime": "2025-05-04T08:25:00", "originPlatform": 1, "destPlatform": 2}]}
+		]}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("TRAIN_API_KEY", "test_api_key")
+	t.Setenv("TRAIN_BASE_URL", server.URL)
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		want     string
+	}{
+		{
+			name: "Schedule table",
+			args: []string{"schedule", "--from", "herzliya", "--to", "tel aviv hashalom", "--at", "2025-05-04 08:00"},
+			want: "08:05   08:25   20m0s",
+		},
+		{
+			name: "Schedule JSON",
+			args: []string{"schedule", "-o", "json", "--from", "3500", "--to", "4600", "--at", "2025-05-04 08:00"},
+			want: `"from_platform": 1`,
+		},
+		{
+			name:     "Schedule without stations",
+			args:     []string{"schedule", "--from", "herzliya"},
+			wantCode: 2,
+		},
+		{
+			name:     "Ambiguous station",
+			args:     []string{"schedule", "--from", "tel aviv", "--to", "4600"},
+			wantCode: 1,
+		},
+		{
+			name: "Stations search",
+			args: []string{"stations", "search", "kfar", "sava"},
+			want: "8700  Kfar Sava-Nordau (A.Kostyuk)",
+		},
+		{
+			name: "Stations search with flags after the query",
+			args: []string{"stations", "search", "herzliya", "-o", "json"},
+			want: `"id": "3500"`,
+		},
+		{
+			name:     "Stations search with a query after --",
+			args:     []string{"stations", "search", "--", "--help"},
+			wantCode: 1,
+		},
+		{
+			name:     "Invalid output",
+			args:     []string{"stations", "search", "-o", "xml", "lod"},
+			wantCode: 2,
+		},
+		{
+			name:     "Unknown command",
+			args:     []string{"deploy"},
+			wantCode: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, &stdout, &stderr); code != tt.wantCode {
+				t.Fatalf("run(%v) = %d, want %d; stderr: %s", tt.args, code, tt.wantCode, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.want) {
+				t.Errorf("run(%v) output %q does not contain %q", tt.args, stdout.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestStationsSearchUsesStationsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stations.json")
+	if err := os.WriteFile(path, []byte(`{"9999": {"Heb": "תחנת ניסיון", "Eng": "Test Station"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	original := train.STATIONS
+	t.Cleanup(func() { train.SetStations(original) })
+
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("TRAIN_API_KEY", "test_api_key")
+	t.Setenv("TRAIN_STATIONS_FILE", path)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"stations", "search", "test", "station"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() = %d; stderr: %s", code, stderr.String())
+	}
+	if want := "9999  Test Station"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("output %q does not contain %q", stdout.String(), want)
+	}
+}
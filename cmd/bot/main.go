@@ -2,18 +2,30 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
+	"rail-go/internal/api"
 	"rail-go/internal/bot"
 	"rail-go/internal/config"
+	"rail-go/internal/lifecycle"
 	"rail-go/internal/logger"
 	"rail-go/internal/scheduler"
+	"rail-go/internal/server"
+	"rail-go/internal/tracing"
+	"rail-go/internal/train"
 )
 
 func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
 
+// runBot runs the Telegram bot until it is asked to shut down and returns the exit code
+func runBot() int {
 	// Create context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -21,11 +33,37 @@ func main() {
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		return 1
 	}
 
 	// Initialize logger
-	log := logger.New()
+	log, err := logger.NewFromConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to initialize logger: %v\n", err)
+		return 1
+	}
+	defer log.Sync()
+	logger.SetDefault(log)
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Setup(ctx, cfg)
+	if err != nil {
+		log.Fatal("failed to initialize tracing", "error", err)
+	}
+
+	// Load stations override
+	if cfg.Train.StationsFile != "" {
+		count, err := train.LoadStations(cfg.Train.StationsFile)
+		if err != nil {
+			log.Fatal("failed to load stations", "error", err)
+		}
+		log.Info("stations loaded", "count", count, "file", cfg.Train.StationsFile)
+	}
+
+	if year := time.Now().Year(); !train.HolidaysCoverYear(year) {
+		log.Warn("holiday dates not listed for this year, only Shabbat is detected", "year", year)
+	}
 
 	// Initialize bot service
 	botService, err := bot.NewService(cfg, log)
@@ -33,26 +71,145 @@ func main() {
 		log.Fatal("failed to initialize bot service", "error", err)
 	}
 
+	// Restore chat profiles saved on the last shutdown
+	if cfg.Bot.StateFile != "" {
+		count, err := botService.LoadState(cfg.Bot.StateFile)
+		if err != nil {
+			log.Fatal("failed to load state", "error", err)
+		}
+		log.Info("state loaded", "profiles", count, "file", cfg.Bot.StateFile)
+	}
+
 	// Initialize scheduler
 	sched := scheduler.New(log)
+	botService.SetTaskController(sched)
 
-	// Create monthly notification task
-	if err := sched.ScheduleMonthlyTask(ctx, botService, cfg.Notifications.DefaultChatID); err != nil {
-		log.Fatal("failed to schedule monthly task", "error", err)
+	// Register notification jobs from config
+	if err := sched.ScheduleNotificationJobs(ctx, botService, cfg); err != nil {
+		log.Fatal("failed to schedule notification jobs", "error", err)
 	}
 
-	// Setup graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	// REST API; without API keys every request is rejected
+	apiHandler := api.New(cfg, botService.TrainService(), log)
+
+	// Reload safe config fields on /reload and SIGHUP. Everything is read and
+	// checked first, so a bad config or stations file changes nothing. Reloads
+	// run one at a time, so two of them cannot leave a mix of their configs.
+	var reloadMu sync.Mutex
+	reload := func() error {
+		reloadMu.Lock()
+		defer reloadMu.Unlock()
+
+		newCfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		var stations map[string]map[string]string
+		if newCfg.Train.StationsFile != "" {
+			if stations, err = train.ReadStations(newCfg.Train.StationsFile); err != nil {
+				return err
+			}
+		}
+		jobs, err := sched.NotificationTasks(botService, newCfg)
+		if err != nil {
+			return err
+		}
+
+		if stations != nil {
+			train.SetStations(stations)
+		}
+		if err := log.SetLevel(newCfg.Log.Level); err != nil {
+			// The level was validated by config.Load
+			log.Error("failed to set log level", "error", err)
+		}
+		botService.ApplyConfig(newCfg)
+		apiHandler.ApplyConfig(newCfg)
+		return sched.ReplaceNotificationJobs(ctx, jobs)
+	}
+	botService.SetReloader(reload)
 
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
 	go func() {
-		<-sigChan
-		log.Info("received shutdown signal")
-		cancel()
+		for range hupChan {
+			if err := reload(); err != nil {
+				log.Error("failed to reload config", "error", err)
+				continue
+			}
+			log.Info("configuration reloaded")
+		}
 	}()
 
+	// Create disruption alerts push task
+	if err := sched.ScheduleAlertsTask(ctx, botService, cfg.Notifications.AlertsInterval); err != nil {
+		log.Fatal("failed to schedule alerts task", "error", err)
+	}
+
+	// Start metrics and health endpoints
+	serverDone := make(chan struct{})
+	if cfg.HTTP.Addr != "" {
+		srv := server.New(cfg.HTTP.Addr, log)
+		srv.AddReadinessCheck("telegram", botService.CheckTelegram)
+		srv.AddReadinessCheck("rail_api", botService.CheckRailAPI)
+		srv.HandleLocal("/loglevel", log.LevelHandler())
+		srv.Handle("/v1/", apiHandler)
+		go func() {
+			defer close(serverDone)
+			if err := srv.Start(ctx); err != nil {
+				log.Error("http server failed", "error", err)
+			}
+		}()
+	} else {
+		close(serverDone)
+	}
+
 	// Start the bot service
-	if err := botService.Start(ctx); err != nil {
-		log.Fatal("bot service failed", "error", err)
+	botErr := make(chan error, 1)
+	go func() {
+		botErr <- botService.Start(ctx)
+	}()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	exitCode := 0
+	select {
+	case sig := <-sigChan:
+		log.Info("received shutdown signal", "signal", sig.String())
+	case err := <-botErr:
+		log.Error("bot service stopped unexpectedly", "error", err)
+		exitCode = 1
+	}
+
+	// Graceful shutdown: cancelling ctx stops Telegram intake, the task timers and
+	// the HTTP server; the hooks then wait for work already in progress.
+	cancel()
+
+	lc := lifecycle.New(log, cfg.Bot.ShutdownTimeout)
+	lc.OnShutdown("send queue", botService.CloseSendQueue)
+	lc.OnShutdown("updates", botService.Drain)
+	lc.OnShutdown("scheduler", sched.Stop)
+	lc.OnShutdown("http server", func(ctx context.Context) error {
+		select {
+		case <-serverDone:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	if cfg.Bot.StateFile != "" {
+		lc.OnShutdown("state", func(context.Context) error {
+			return botService.SaveState(cfg.Bot.StateFile)
+		})
+	}
+	lc.OnShutdown("tracing", shutdownTracing)
+
+	if err := lc.Shutdown(); err != nil {
+		exitCode = 1
 	}
+	log.Info("shutdown complete")
+
+	// Sync errors on stdout/stderr are expected and not worth reporting
+	_ = log.Sync()
+	return exitCode
 }
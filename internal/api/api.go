@@ -0,0 +1,224 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"rail-go/internal/config"
+	"rail-go/internal/logger"
+	"rail-go/internal/metrics"
+	"rail-go/internal/ratelimit"
+	"rail-go/internal/train"
+)
+
+// maxTrips is the largest limit a trips request may ask for
+const maxTrips = 20
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// Handler serves the REST API under /v1
+type Handler struct {
+	mu      sync.RWMutex
+	logger  *logger.Logger
+	trains  *train.Service
+	keys    [][]byte
+	limiter *ratelimit.Limiter
+	mux     *http.ServeMux
+	now     func() time.Time
+}
+
+func New(cfg *config.Config, trainService *train.Service, log *logger.Logger) *Handler {
+	h := &Handler{
+		logger: log,
+		trains: trainService,
+		limiter: ratelimit.New(cfg.RateLimit.UserPerMinute, cfg.RateLimit.UserBurst,
+			cfg.RateLimit.GlobalPerMinute, cfg.RateLimit.GlobalBurst),
+		mux: http.NewServeMux(),
+		now: time.Now,
+	}
+	h.setKeys(cfg.API.Keys)
+
+	h.mux.HandleFunc("GET /v1/openapi.yaml", h.serveSpec)
+	h.mux.Handle("GET /v1/stations", h.protect("stations", h.serveStations))
+	h.mux.Handle("GET /v1/trips", h.protect("trips", h.serveTrips))
+	h.mux.HandleFunc("/v1/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not found")
+	})
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// ApplyConfig updates the API keys and rate limits after a config reload
+func (h *Handler) ApplyConfig(cfg *config.Config) {
+	h.setKeys(cfg.API.Keys)
+	h.limiter.SetLimits(cfg.RateLimit.UserPerMinute, cfg.RateLimit.UserBurst,
+		cfg.RateLimit.GlobalPerMinute, cfg.RateLimit.GlobalBurst)
+}
+
+func (h *Handler) setKeys(keys []string) {
+	hashed := make([][]byte, len(keys))
+	for i, key := range keys {
+		hashed[i] = hashKey(key)
+	}
+
+	h.mu.Lock()
+	h.keys = hashed
+	h.mu.Unlock()
+}
+
+// hashKey lets keys be compared in constant time regardless of their length
+func hashKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+// authenticate returns the client ID of a valid API key, taken from the
+// X-API-Key header or an "Authorization: Bearer" header
+func (h *Handler) authenticate(r *http.Request) (string, bool) {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		key, _ = strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	if key == "" {
+		return "", false
+	}
+
+	hashed := hashKey(key)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, valid := range h.keys {
+		if subtle.ConstantTimeCompare(hashed, valid) == 1 {
+			// Never log or key buckets by the secret itself
+			return hex.EncodeToString(hashed[:4]), true
+		}
+	}
+	return "", false
+}
+
+// protect wraps a route with API-key auth, rate limiting, request logging and metrics
+func (h *Handler) protect(route string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		log := h.logger.With(
+			"correlation_id", logger.NewCorrelationID(),
+			"route", route)
+
+		defer func() {
+			metrics.APIRequests.WithLabelValues(route, strconv.Itoa(rec.status)).Inc()
+			metrics.APIDuration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+			log.Info("api request",
+				"status", rec.status,
+				"duration", time.Since(start).String())
+		}()
+
+		client, ok := h.authenticate(r)
+		if !ok {
+			rec.Header().Set("WWW-Authenticate", `Bearer realm="rail-go"`)
+			writeError(rec, http.StatusUnauthorized, "missing or invalid API key")
+			return
+		}
+		log = log.With("client", client)
+
+		if !h.limiter.Allow(client) {
+			rec.Header().Set("Retry-After", "60")
+			writeError(rec, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+
+		next(rec, r.WithContext(logger.NewContext(r.Context(), log)))
+	})
+}
+
+func (h *Handler) serveSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPISpec)
+}
+
+func (h *Handler) serveStations(w http.ResponseWriter, r *http.Request) {
+	var stations []train.Station
+	if q := r.URL.Query().Get("q"); q != "" {
+		stations = train.FindStations(q)
+	} else {
+		stations = train.AllStations()
+	}
+	if stations == nil {
+		stations = []train.Station{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"stations": stations})
+}
+
+func (h *Handler) serveTrips(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, to := query.Get("from"), query.Get("to")
+	if from == "" || to == "" {
+		writeError(w, http.StatusBadRequest, "from and to are required")
+		return
+	}
+
+	at, err := train.ParseTime(query.Get("at"), h.now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := 5
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxTrips {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxTrips))
+			return
+		}
+	}
+
+	schedule, err := h.trains.PlanTrips(r.Context(), from, to, at)
+	if errors.Is(err, train.ErrUnknownStation) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("failed to get trips", "error", err)
+		writeError(w, http.StatusBadGateway, "failed to get trips from Israel Railways")
+		return
+	}
+	if len(schedule.Trips) > limit {
+		schedule.Trips = schedule.Trips[:limit]
+	}
+
+	writeJSON(w, http.StatusOK, schedule)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// statusRecorder remembers the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
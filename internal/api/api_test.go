@@ -0,0 +1,145 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"rail-go/internal/logger"
+	"rail-go/internal/train"
+)
+
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	rail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result": {"travels": [
+			{"trains": [{"orignStation": 3500, "destinationStation": 4600, "departureTime": "2025-05-04T08:05:00", "arrivalTime": "2025-05-04T08:25:00", "originPlatform": 1, "destPlatform": 2}]}
+		]}}`))
+	}))
+	t.Cleanup(rail.Close)
+
+	cfg := train.NewTestConfig()
+	cfg.Train.BaseURL = rail.URL
+	cfg.API.Keys = []string{"secret-key"}
+	cfg.RateLimit.UserBurst = 100
+	log := logger.New()
+	return New(cfg, train.NewService(cfg, log), log)
+}
+
+func TestHandler(t *testing.T) {
+	h := newTestHandler(t)
+
+	tests := []struct {
+		name       string
+		path       string
+		header     string
+		wantStatus int
+		want       string
+	}{
+		{
+			name:       "Missing key",
+			path:       "/v1/stations",
+			wantStatus: http.StatusUnauthorized,
+			want:       "missing or invalid API key",
+		},
+		{
+			name:       "Wrong key",
+			path:       "/v1/stations",
+			header:     "X-API-Key: other",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Station search",
+			path:       "/v1/stations?q=herzliya",
+			header:     "X-API-Key: secret-key",
+			wantStatus: http.StatusOK,
+			want:       `"id":"3500"`,
+		},
+		{
+			name:       "Bearer token",
+			path:       "/v1/stations",
+			header:     "Authorization: Bearer secret-key",
+			wantStatus: http.StatusOK,
+			want:       `"id":"4600"`,
+		},
+		{
+			name:       "Trips",
+			path:       "/v1/trips?from=herzliya&to=4600&at=2025-05-04T08:00",
+			header:     "X-API-Key: secret-key",
+			wantStatus: http.StatusOK,
+			want:       `"from_platform":1`,
+		},
+		{
+			name:       "Unknown station",
+			path:       "/v1/trips?from=springfield&to=4600",
+			header:     "X-API-Key: secret-key",
+			wantStatus: http.StatusBadRequest,
+			want:       "unknown station",
+		},
+		{
+			name:       "Missing parameters",
+			path:       "/v1/trips?from=3500",
+			header:     "X-API-Key: secret-key",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Invalid limit",
+			path:       "/v1/trips?from=3500&to=4600&limit=100",
+			header:     "X-API-Key: secret-key",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Spec without key",
+			path:       "/v1/openapi.yaml",
+			wantStatus: http.StatusOK,
+			want:       "openapi: 3.0.3",
+		},
+		{
+			name:       "Unknown route",
+			path:       "/v1/nothing",
+			header:     "X-API-Key: secret-key",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := get(h, tt.path, tt.header)
+			if status != tt.wantStatus {
+				t.Errorf("GET %s status = %d, want %d; body: %s", tt.path, status, tt.wantStatus, body)
+			}
+			if !strings.Contains(body, tt.want) {
+				t.Errorf("GET %s body %q does not contain %q", tt.path, body, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerRateLimit(t *testing.T) {
+	h := newTestHandler(t)
+	cfg := train.NewTestConfig()
+	cfg.API.Keys = []string{"secret-key"}
+	cfg.RateLimit.UserPerMinute = 1
+	cfg.RateLimit.UserBurst = 1
+	h.ApplyConfig(cfg)
+
+	if status, _ := get(h, "/v1/stations", "X-API-Key: secret-key"); status != http.StatusOK {
+		t.Fatalf("first request status = %d, want 200", status)
+	}
+	if status, _ := get(h, "/v1/stations", "X-API-Key: secret-key"); status != http.StatusTooManyRequests {
+		t.Errorf("second request status = %d, want 429", status)
+	}
+}
+
+func get(h http.Handler, path, header string) (int, string) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if name, value, ok := strings.Cut(header, ": "); ok {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	body, _ := io.ReadAll(rec.Body)
+	return rec.Code, string(body)
+}
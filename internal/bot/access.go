@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"rail-go/internal/logger"
+	"rail-go/internal/metrics"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// slowDownNoticeInterval limits how often a rate-limited user is told to slow down
+const slowDownNoticeInterval = 30 * time.Second
+
+// isAllowed checks the user and chat against the deny-list and, when set, the allow-list.
+// Admins are always allowed.
+func (s *Service) isAllowed(userID, chatID int64) bool {
+	if s.isAdmin(userID) {
+		return true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, id := range s.config.Bot.DenyIDs {
+		if id == userID || id == chatID {
+			return false
+		}
+	}
+	if len(s.config.Bot.AllowIDs) == 0 {
+		return true
+	}
+	for _, id := range s.config.Bot.AllowIDs {
+		if id == userID || id == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+// admitUpdate applies access control and rate limiting to an incoming update.
+// It returns false when the update must not be handled.
+func (s *Service) admitUpdate(ctx context.Context, update tgbotapi.Update) bool {
+	user := update.SentFrom()
+	if user == nil {
+		return true
+	}
+	// Inline queries have no chat, so only the user is checked
+	chat := update.FromChat()
+	var chatID int64
+	if chat != nil {
+		chatID = chat.ID
+	}
+
+	if !s.isAllowed(user.ID, chatID) {
+		s.stats.denied.Add(1)
+		metrics.UpdatesRejected.WithLabelValues("denied").Inc()
+		logger.FromContext(ctx).Info("update from unauthorized user ignored")
+		return false
+	}
+
+	// Telegram sends an inline query on every keystroke, so they have a budget
+	// of their own and do not use up the one of messages and buttons
+	limiter := s.limiter
+	if update.InlineQuery != nil {
+		limiter = s.inlineLimiter
+	}
+	if s.isAdmin(user.ID) || limiter.Allow(strconv.FormatInt(user.ID, 10)) {
+		return true
+	}
+
+	s.stats.rateLimited.Add(1)
+	metrics.UpdatesRejected.WithLabelValues("rate_limited").Inc()
+	logger.FromContext(ctx).Info("update rate limited")
+
+	if update.CallbackQuery != nil {
+		callback := tgbotapi.NewCallback(update.CallbackQuery.ID, "⏳ יותר מדי בקשות, נסה שוב בעוד רגע.")
+		if _, err := s.bot.Request(callback); err != nil {
+			logger.FromContext(ctx).Error("failed to acknowledge callback", "error", err)
+		}
+		return false
+	}
+	if chat == nil {
+		return false
+	}
+
+	now := time.Now()
+	last, loaded := s.slowDownNotices.Load(user.ID)
+	if loaded && now.Sub(last.(time.Time)) < slowDownNoticeInterval {
+		return false
+	}
+	s.noteSlowDown(user.ID, now)
+
+	if err := s.SendMessage(ctx, chat.ID, "⏳ שלחת יותר מדי בקשות. אנא המתן מעט ונסה שוב."); err != nil {
+		logger.FromContext(ctx).Error("failed to send slow down message", "error", err)
+	}
+	return false
+}
+
+// noteSlowDown records when a user was told to slow down. Notices older than
+// slowDownNoticeInterval no longer hold anything back, so they are dropped, at
+// most once per interval.
+func (s *Service) noteSlowDown(userID int64, now time.Time) {
+	s.slowDownNotices.Store(userID, now)
+
+	last := s.slowDownPruned.Load()
+	if now.Sub(time.Unix(0, last)) < slowDownNoticeInterval || !s.slowDownPruned.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	s.slowDownNotices.Range(func(key, v any) bool {
+		if now.Sub(v.(time.Time)) >= slowDownNoticeInterval {
+			s.slowDownNotices.CompareAndDelete(key, v)
+		}
+		return true
+	})
+}
@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rail-go/internal/ratelimit"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestAdmitInlineQueries(t *testing.T) {
+	s, telegram := newTestService(t)
+	s.limiter = ratelimit.New(1, 1, 0, 0)
+	s.inlineLimiter = ratelimit.New(60, 3, 0, 0)
+	ctx := context.Background()
+
+	user := &tgbotapi.User{ID: 7}
+	message := tgbotapi.Update{Message: &tgbotapi.Message{From: user, Chat: &tgbotapi.Chat{ID: 7, Type: "private"}}}
+	inline := tgbotapi.Update{InlineQuery: &tgbotapi.InlineQuery{ID: "q1", From: user, Query: "הרצ"}}
+
+	if !s.admitUpdate(ctx, message) {
+		t.Fatal("first message rejected")
+	}
+	for i := range 3 {
+		if !s.admitUpdate(ctx, inline) {
+			t.Fatalf("inline query %d rejected", i)
+		}
+	}
+	if s.admitUpdate(ctx, inline) {
+		t.Error("inline query after the inline burst admitted")
+	}
+	if s.admitUpdate(ctx, message) {
+		t.Error("message after the user burst admitted")
+	}
+
+	// Only the rejected message is told to slow down
+	assertCalls(t, telegram.requests(), []telegramCall{
+		{method: "sendMessage", params: map[string][]string{"text": {"יותר מדי בקשות"}}},
+	})
+}
+
+func TestNoteSlowDownPrunes(t *testing.T) {
+	s, _ := newTestService(t)
+	start := time.Date(2025, 5, 4, 8, 0, 0, 0, time.UTC)
+
+	s.noteSlowDown(7, start)
+	s.noteSlowDown(8, start.Add(slowDownNoticeInterval/2))
+	s.noteSlowDown(9, start.Add(slowDownNoticeInterval))
+
+	for userID, want := range map[int64]bool{7: false, 8: true, 9: true} {
+		if _, ok := s.slowDownNotices.Load(userID); ok != want {
+			t.Errorf("notice of user %d kept = %v, want %v", userID, ok, want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"rail-go/internal/config"
+	"rail-go/internal/logger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// stats counts what the bot handled since it started
+type stats struct {
+	startedAt time.Time
+	messages  atomic.Int64
+	callbacks atomic.Int64
+	errors    atomic.Int64
+	// inlineQueries counts "@bot" lookups from any chat
+	inlineQueries atomic.Int64
+	// rejected updates
+	denied      atomic.Int64
+	rateLimited atomic.Int64
+}
+
+// isAdmin reports whether the user may run admin commands
+func (s *Service) isAdmin(userID int64) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, id := range s.config.Bot.AdminIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectNonAdmin answers a non-admin that tried to run an admin command
+func (s *Service) rejectNonAdmin(ctx context.Context, msg *tgbotapi.Message) error {
+	logger.FromContext(ctx).Info("admin command rejected")
+	return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("%s פקודה זו זמינה למנהלים בלבד.", warningEmoji))
+}
+
+func (s *Service) handleStats(ctx context.Context, msg *tgbotapi.Message) error {
+	knownChats := 0
+	s.profiles.Range(func(_, _ any) bool {
+		knownChats++
+		return true
+	})
+
+	tasks := 0
+	if s.tasks != nil {
+		tasks = len(s.tasks.Tasks())
+	}
+
+	text := fmt.Sprintf("📊 סטטיסטיקה\n"+
+		"זמן פעילות: %s\n"+
+		"משתמשים מוכרים: %d\n"+
+		"הודעות: %d\n"+
+		"לחיצות על כפתורים: %d\n"+
+		"חיפושים מוטמעים: %d\n"+
+		"שגיאות: %d\n"+
+		"נחסמו (הרשאות): %d\n"+
+		"נחסמו (הגבלת קצב): %d\n"+
+		"רשומות במטמון: %d\n"+
+		"משימות מתוזמנות: %d\n"+
+		"מצב דיבאג: %v",
+		time.Since(s.stats.startedAt).Truncate(time.Second),
+		knownChats,
+		s.stats.messages.Load(),
+		s.stats.callbacks.Load(),
+		s.stats.inlineQueries.Load(),
+		s.stats.errors.Load(),
+		s.stats.denied.Load(),
+		s.stats.rateLimited.Load(),
+		s.trainService.CacheSize(),
+		tasks,
+		s.logger.Level() == "debug")
+
+	return s.SendMessage(ctx, msg.Chat.ID, text)
+}
+
+// handleBroadcast sends a message to every chat the bot knows. The messages are
+// paced by the send queue, so they are sent in the background and the admin is
+// told the outcome when they are done.
+func (s *Service) handleBroadcast(ctx context.Context, msg *tgbotapi.Message, text string) error {
+	if text == "" {
+		return s.SendMessage(ctx, msg.Chat.ID, "שימוש: /broadcast <הודעה>")
+	}
+
+	var chatIDs []int64
+	s.profiles.Range(func(key, _ any) bool {
+		chatIDs = append(chatIDs, key.(int64))
+		return true
+	})
+
+	logger.FromContext(ctx).Info("broadcast started", "chats", len(chatIDs))
+	err := s.SendMessage(ctx, msg.Chat.ID,
+		fmt.Sprintf("%s ההודעה נשלחת ל-%d צ'אטים. אעדכן כשהשליחה תסתיים.", successEmoji, len(chatIDs)))
+
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		s.broadcast(withPriority(ctx, priorityBackground), msg.Chat.ID, chatIDs, text)
+	}()
+	return err
+}
+
+// broadcast sends text to chatIDs and reports the outcome to adminChatID. It
+// stops when the send queue closes for a shutdown.
+func (s *Service) broadcast(ctx context.Context, adminChatID int64, chatIDs []int64, text string) {
+	sent, failed := 0, 0
+	for _, chatID := range chatIDs {
+		err := s.SendMessage(ctx, chatID, text)
+		if errors.Is(err, errSendQueueClosed) {
+			logger.FromContext(ctx).Warn("broadcast stopped by shutdown", "sent", sent, "failed", failed)
+			return
+		}
+		if err != nil {
+			logger.FromContext(ctx).Error("failed to broadcast message", "target_chat_id", chatID, "error", err)
+			failed++
+		} else {
+			sent++
+		}
+	}
+
+	logger.FromContext(ctx).Info("broadcast completed", "sent", sent, "failed", failed)
+	if err := s.SendMessage(ctx, adminChatID,
+		fmt.Sprintf("%s ההודעה נשלחה ל-%d צ'אטים (%d נכשלו).", successEmoji, sent, failed)); err != nil {
+		logger.FromContext(ctx).Error("failed to report broadcast", "error", err)
+	}
+}
+
+// handleReload reloads the configuration and the stations file
+func (s *Service) handleReload(ctx context.Context, msg *tgbotapi.Message) error {
+	if s.reloader == nil {
+		return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("%s טעינה מחדש אינה זמינה.", warningEmoji))
+	}
+
+	if err := s.reloader(); err != nil {
+		logger.FromContext(ctx).Error("failed to reload config", "error", err)
+		return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("%s טעינת ההגדרות נכשלה: %v", warningEmoji, err))
+	}
+
+	logger.FromContext(ctx).Info("configuration reloaded")
+	return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("%s ההגדרות נטענו מחדש.", successEmoji))
+}
+
+// SetReloader sets the function the /reload command uses to reload the configuration
+func (s *Service) SetReloader(reload func() error) {
+	s.reloader = reload
+}
+
+// ApplyConfig applies the fields of a reloaded configuration that are safe to change
+// while running: admins, allow/deny lists, rate limits and notification templates
+func (s *Service) ApplyConfig(cfg *config.Config) {
+	s.mu.Lock()
+	s.config.Bot.AdminIDs = cfg.Bot.AdminIDs
+	s.config.Bot.AllowIDs = cfg.Bot.AllowIDs
+	s.config.Bot.DenyIDs = cfg.Bot.DenyIDs
+	s.config.Notifications.TimeFormat = cfg.Notifications.TimeFormat
+	s.config.Notifications.Templates = cfg.Notifications.Templates
+	s.config.RateLimit = cfg.RateLimit
+	s.config.Log.Level = cfg.Log.Level
+	s.mu.Unlock()
+
+	s.limiter.SetLimits(cfg.RateLimit.UserPerMinute, cfg.RateLimit.UserBurst,
+		cfg.RateLimit.GlobalPerMinute, cfg.RateLimit.GlobalBurst)
+	s.inlineLimiter.SetLimits(cfg.RateLimit.InlinePerMinute, cfg.RateLimit.InlineBurst, 0, 0)
+	s.sendQueue.setLimits(sendLimitsFromConfig(cfg))
+	s.trainService.FlushCache()
+}
+
+// handleDebug turns debug logging on or off, or toggles it. The debug output of
+// the Telegram library is only set on startup with BOT_DEBUG, because the library
+// reads it from its polling goroutine.
+func (s *Service) handleDebug(ctx context.Context, msg *tgbotapi.Message, args string) error {
+	debug := s.logger.Level() != "debug"
+	switch args {
+	case "on":
+		debug = true
+	case "off":
+		debug = false
+	}
+
+	level := "debug"
+	if !debug {
+		s.mu.RLock()
+		level = s.config.Log.Level
+		s.mu.RUnlock()
+		if level == "debug" {
+			level = "info"
+		}
+	}
+	if err := s.logger.SetLevel(level); err != nil {
+		return fmt.Errorf("failed to set log level: %w", err)
+	}
+
+	logger.FromContext(ctx).Warn("log level changed", "log_level", level)
+	return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("%s מצב דיבאג: %v", successEmoji, debug))
+}
+
+// handleLogLevel shows the log level, or changes it when one is given
+func (s *Service) handleLogLevel(ctx context.Context, msg *tgbotapi.Message, level string) error {
+	if level == "" {
+		return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("רמת לוג נוכחית: %s", s.logger.Level()))
+	}
+
+	if err := s.logger.SetLevel(level); err != nil {
+		return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("%s רמת לוג לא תקינה: %v", warningEmoji, err))
+	}
+
+	logger.FromContext(ctx).Warn("log level changed", "log_level", level)
+	return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("%s רמת לוג: %s", successEmoji, s.logger.Level()))
+}
@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestBroadcast(t *testing.T) {
+	s, telegram := newTestService(t)
+	s.profile(5)
+	s.profile(6)
+
+	msg := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 42, Type: "private"}, From: &tgbotapi.User{ID: 42}}
+	if err := s.handleBroadcast(context.Background(), msg, "עדכון"); err != nil {
+		t.Fatalf("handleBroadcast() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Drain(ctx); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+	assertCalls(t, telegram.requests(), []telegramCall{
+		{method: "sendMessage", params: map[string][]string{"chat_id": {"42"}, "text": {"נשלחת ל-2"}}},
+		{method: "sendMessage", params: map[string][]string{"text": {"עדכון"}}},
+		{method: "sendMessage", params: map[string][]string{"text": {"עדכון"}}},
+		{method: "sendMessage", params: map[string][]string{"chat_id": {"42"}, "text": {"נשלחה ל-2"}}},
+	})
+}
+
+func TestHandleDebug(t *testing.T) {
+	s, _ := newTestService(t)
+	s.config.Log.Level = "warn"
+	libraryDebug := s.bot.Debug
+	msg := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 42, Type: "private"}, From: &tgbotapi.User{ID: 42}}
+
+	for _, step := range []struct {
+		args string
+		want string
+	}{
+		{args: "on", want: "debug"},
+		{args: "off", want: "warn"},
+		{args: "", want: "debug"},
+		{args: "", want: "warn"},
+	} {
+		if err := s.handleDebug(context.Background(), msg, step.args); err != nil {
+			t.Fatalf("handleDebug(%q) error = %v", step.args, err)
+		}
+		if got := s.logger.Level(); got != step.want {
+			t.Errorf("handleDebug(%q): level = %s, want %s", step.args, got, step.want)
+		}
+	}
+	if s.bot.Debug != libraryDebug {
+		t.Error("handleDebug() changed the debug flag of the Telegram library")
+	}
+}
@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"rail-go/internal/logger"
+	"rail-go/internal/train"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleAlerts lists the current disruptions relevant to the chat's saved routes
+func (s *Service) handleAlerts(ctx context.Context, msg *tgbotapi.Message) error {
+	logger.FromContext(ctx).Info("handling alerts request")
+
+	alerts, err := s.trainService.GetAlerts(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to get alerts", "error", err)
+		return fmt.Errorf("failed to get alerts: %w", err)
+	}
+
+	p := s.profile(msg.Chat.ID)
+	routes := append(p.savedRoutes(), p.alertRoutes()...)
+	if len(routes) > 0 {
+		alerts = filterAlerts(alerts, routes, true)
+	}
+
+	if len(alerts) == 0 {
+		return s.SendMessage(ctx, msg.Chat.ID,
+			fmt.Sprintf("%s אין כרגע הודעות על שיבושים בקווים שלך.", successEmoji))
+	}
+
+	return s.SendMessages(ctx, msg.Chat.ID, s.trainService.FormatAlerts(alerts))
+}
+
+// NotifyAlerts pushes new disruptions to every chat with an affected saved route.
+// Alerts that left the feed are forgotten, so the saved state does not grow with
+// every disruption ever pushed.
+func (s *Service) NotifyAlerts(ctx context.Context) error {
+	alerts, err := s.trainService.GetAlerts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get alerts: %w", err)
+	}
+	current := make(map[string]bool, len(alerts))
+	for _, alert := range alerts {
+		current[alert.ID] = true
+	}
+
+	var lastErr error
+	s.profiles.Range(func(_, value any) bool {
+		p := value.(*userProfile)
+		p.mu.Lock()
+		muted := p.AlertsMuted
+		for id := range p.NotifiedAlerts {
+			if !current[id] {
+				delete(p.NotifiedAlerts, id)
+			}
+		}
+		p.mu.Unlock()
+
+		routes := p.alertRoutes()
+		if !muted {
+			routes = append(routes, p.savedRoutes()...)
+		}
+
+		for _, alert := range filterAlerts(alerts, routes, false) {
+			p.mu.Lock()
+			notified := p.NotifiedAlerts[alert.ID]
+			p.mu.Unlock()
+			if notified {
+				continue
+			}
+
+			if err := s.SendMessage(ctx, p.ChatID, s.trainService.FormatAlert(alert)); err != nil {
+				s.logger.Error("failed to push alert",
+					"chat_id", p.ChatID,
+					"alert_id", alert.ID,
+					"error", err)
+				lastErr = err
+				continue
+			}
+
+			p.mu.Lock()
+			p.NotifiedAlerts[alert.ID] = true
+			p.mu.Unlock()
+
+			s.logger.Info("alert pushed",
+				"chat_id", p.ChatID,
+				"alert_id", alert.ID)
+		}
+		return ctx.Err() == nil
+	})
+
+	return lastErr
+}
+
+// filterAlerts keeps the alerts affecting at least one of the routes
+func filterAlerts(alerts []train.Alert, routes []route, includeGeneral bool) []train.Alert {
+	var relevant []train.Alert
+	for _, alert := range alerts {
+		if includeGeneral && alert.IsGeneral() {
+			relevant = append(relevant, alert)
+			continue
+		}
+		for _, r := range routes {
+			if alert.AffectsRoute(r.From, r.To) {
+				relevant = append(relevant, alert)
+				break
+			}
+		}
+	}
+	return relevant
+}
@@ -0,0 +1,43 @@
+package bot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"rail-go/internal/logger"
+	"rail-go/internal/train"
+)
+
+func TestNotifyAlertsForgetsOldAlerts(t *testing.T) {
+	rail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result": [
+			{"updateID": 1, "updateHeader": "עבודות תשתית", "stations": [3500, 4600], "endValidity": "2999-01-01T00:00:00"}
+		]}`))
+	}))
+	t.Cleanup(rail.Close)
+
+	s, telegram := newTestService(t)
+	cfg := train.NewTestConfig()
+	cfg.Train.UpdatesURL = rail.URL
+	s.trainService = train.NewService(cfg, logger.New())
+
+	s.rememberRoute(7, "3500", "4600")
+	p := s.profile(7)
+	p.NotifiedAlerts["0"] = true
+
+	for range 2 {
+		if err := s.NotifyAlerts(context.Background()); err != nil {
+			t.Fatalf("NotifyAlerts() error = %v", err)
+		}
+	}
+
+	if got := len(p.NotifiedAlerts); got != 1 || !p.NotifiedAlerts["1"] {
+		t.Errorf("NotifiedAlerts = %v, want only the current alert", p.NotifiedAlerts)
+	}
+	// The current alert is pushed once
+	assertCalls(t, telegram.requests(), []telegramCall{
+		{method: "sendMessage", params: map[string][]string{"text": {"עבודות תשתית"}}},
+	})
+}
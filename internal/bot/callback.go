@@ -0,0 +1,177 @@
+package bot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"rail-go/internal/logger"
+	"rail-go/internal/metrics"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// callbackAction says what an inline button does
+type callbackAction string
+
+const (
+	actionHome        callbackAction = "h"
+	actionWork        callbackAction = "w"
+	actionOther       callbackAction = "o"
+	actionSearch      callbackAction = "s"
+	actionFromStation callbackAction = "fs"
+	actionToStation   callbackAction = "ts"
+	actionClarify     callbackAction = "cs"
+	actionRoute       callbackAction = "r"
+	actionFavorite    callbackAction = "f"
+	actionReverse     callbackAction = "rv"
+	actionLater       callbackAction = "l"
+	actionAlert       callbackAction = "a"
+)
+
+var callbackActions = map[callbackAction]bool{
+	actionHome: true, actionWork: true, actionOther: true, actionSearch: true,
+	actionFromStation: true, actionToStation: true, actionClarify: true,
+	actionRoute: true, actionFavorite: true,
+	actionReverse: true, actionLater: true, actionAlert: true,
+}
+
+const (
+	// callbackVersion is bumped when the payload of an action changes meaning,
+	// so buttons of older messages are recognized as stale
+	callbackVersion = 1
+	// maxCallbackData is Telegram's limit on callback data, in bytes
+	maxCallbackData = 64
+	// callbackSignatureSize is the number of HMAC bytes kept in the data
+	callbackSignatureSize = 8
+	callbackSeparator     = "|"
+)
+
+var (
+	// errStaleCallback marks buttons from older bot versions or flows that no longer apply
+	errStaleCallback = errors.New("stale callback data")
+	// errInvalidCallback marks data that was not produced by this bot
+	errInvalidCallback = errors.New("invalid callback data")
+)
+
+// callbackData is a decoded button press
+type callbackData struct {
+	action  callbackAction
+	payload string
+}
+
+// callbackCodec writes and reads callback data as "<version>|<action>|<payload>",
+// followed by "|<signature>" when a key is set. The zero value does not sign.
+type callbackCodec struct {
+	key []byte
+}
+
+func newCallbackCodec(secret string) callbackCodec {
+	if secret == "" {
+		return callbackCodec{}
+	}
+	return callbackCodec{key: []byte(secret)}
+}
+
+// encode returns the callback data of a button. Payloads are station IDs and
+// routes, which stay well within maxCallbackData even when signed.
+func (c callbackCodec) encode(action callbackAction, payload string) string {
+	data := strconv.Itoa(callbackVersion) + callbackSeparator + string(action) + callbackSeparator + payload
+	if c.key != nil {
+		data += callbackSeparator + c.sign(data)
+	}
+	return data
+}
+
+// decode reads callback data written by encode
+func (c callbackCodec) decode(data string) (callbackData, error) {
+	if len(data) > maxCallbackData {
+		return callbackData{}, errInvalidCallback
+	}
+
+	parts := strings.Split(data, callbackSeparator)
+	if len(parts) < 3 {
+		// Data from before the versioned format, e.g. "home" or a bare station ID
+		return callbackData{}, errStaleCallback
+	}
+	if version, err := strconv.Atoi(parts[0]); err != nil || version != callbackVersion {
+		return callbackData{}, errStaleCallback
+	}
+
+	if c.key != nil {
+		if len(parts) != 4 {
+			return callbackData{}, errInvalidCallback
+		}
+		signed := strings.Join(parts[:3], callbackSeparator)
+		if !hmac.Equal([]byte(parts[3]), []byte(c.sign(signed))) {
+			return callbackData{}, errInvalidCallback
+		}
+	} else if len(parts) != 3 {
+		// Signed by a previous key; the signature cannot be checked any more
+		return callbackData{}, errStaleCallback
+	}
+
+	action := callbackAction(parts[1])
+	if !callbackActions[action] {
+		return callbackData{}, errStaleCallback
+	}
+	return callbackData{action: action, payload: parts[2]}, nil
+}
+
+func (c callbackCodec) sign(data string) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:callbackSignatureSize])
+}
+
+// stationSelectionStates maps each station button to the conversation state it answers
+var stationSelectionStates = map[callbackAction]string{
+	actionFromStation: "awaitingFromStationSelection",
+	actionToStation:   "awaitingToStationSelection",
+	actionClarify:     "clarifyingStation",
+}
+
+// handleStaleCallback answers a button that can no longer be handled, e.g. from
+// an older bot version or with altered data, and lets the user start over
+func (s *Service) handleStaleCallback(ctx context.Context, query *tgbotapi.CallbackQuery, err error) error {
+	reason := "stale_callback"
+	if errors.Is(err, errInvalidCallback) {
+		reason = "invalid_callback"
+		logger.FromContext(ctx).Warn("callback data rejected", "error", err)
+	} else {
+		logger.FromContext(ctx).Info("stale callback pressed", "error", err)
+	}
+	metrics.UpdatesRejected.WithLabelValues(reason).Inc()
+
+	callback := tgbotapi.NewCallback(query.ID, "⌛ הכפתור הזה כבר לא בתוקף")
+	if _, err := s.bot.Request(callback); err != nil {
+		logger.FromContext(ctx).Error("failed to acknowledge callback", "error", err)
+	}
+	if query.Message == nil {
+		return nil
+	}
+	return s.restartFlow(ctx, queryConversation(query), query.Message)
+}
+
+// restartFlow drops the conversation in progress and the dead buttons of msg,
+// and offers the route menu again
+func (s *Service) restartFlow(ctx context.Context, conv conversation, msg *tgbotapi.Message) error {
+	chatID := msg.Chat.ID
+	s.userState.Delete(conv.stateKey())
+
+	// The text of the old message may still be useful, only its keyboard goes.
+	// In groups the buttons may still work for the member whose flow showed them.
+	if !conv.inGroup() {
+		edit := tgbotapi.NewEditMessageReplyMarkup(chatID, msg.MessageID, newKeyboard().inlineMarkup())
+		if err := s.request(ctx, chatID, edit); err != nil {
+			logger.FromContext(ctx).Error("failed to remove stale keyboard", "error", err)
+		}
+	}
+	return s.sendRouteMenu(ctx, chatID,
+		fmt.Sprintf("%s הכפתור הזה כבר לא בתוקף. נתחיל מחדש, בחר יעד:", warningEmoji))
+}
@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestCallbackCodec(t *testing.T) {
+	unsigned := newCallbackCodec("")
+	signed := newCallbackCodec("secret")
+	otherKey := newCallbackCodec("other-secret")
+
+	route := encodeRoute(route{From: "4600", To: "8700"})
+	tampered := strings.Replace(signed.encode(actionRoute, route), "8700", "3500", 1)
+
+	tests := []struct {
+		name    string
+		codec   callbackCodec
+		data    string
+		want    callbackData
+		wantErr error
+	}{
+		{name: "Unsigned", codec: unsigned, data: unsigned.encode(actionRoute, route), want: callbackData{actionRoute, route}},
+		{name: "Signed", codec: signed, data: signed.encode(actionFromStation, "4600"), want: callbackData{actionFromStation, "4600"}},
+		{name: "Empty payload", codec: signed, data: signed.encode(actionHome, ""), want: callbackData{actionHome, ""}},
+		{name: "Legacy action", codec: unsigned, data: "home", wantErr: errStaleCallback},
+		{name: "Legacy station ID", codec: signed, data: "4600", wantErr: errStaleCallback},
+		{name: "Legacy route", codec: unsigned, data: "saved:4600:8700", wantErr: errStaleCallback},
+		{name: "Other version", codec: unsigned, data: "2|r|" + route, wantErr: errStaleCallback},
+		{name: "Unknown action", codec: unsigned, data: "1|x|" + route, wantErr: errStaleCallback},
+		{name: "Signed before signing was disabled", codec: unsigned, data: signed.encode(actionRoute, route), wantErr: errStaleCallback},
+		{name: "Tampered payload", codec: signed, data: tampered, wantErr: errInvalidCallback},
+		{name: "Other key", codec: signed, data: otherKey.encode(actionRoute, route), wantErr: errInvalidCallback},
+		{name: "Missing signature", codec: signed, data: unsigned.encode(actionRoute, route), wantErr: errInvalidCallback},
+		{name: "Too long", codec: unsigned, data: "1|r|" + strings.Repeat("1", maxCallbackData), wantErr: errInvalidCallback},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.codec.decode(tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("decode(%q) error = %v, want %v", tt.data, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("decode(%q) = %+v, want %+v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallbackDataFits(t *testing.T) {
+	codec := newCallbackCodec("secret")
+	// The longest payload: a route between two long station IDs
+	data := codec.encode(actionFavorite, encodeRoute(route{From: "999999", To: "999999"}))
+	if len(data) > maxCallbackData {
+		t.Errorf("len(%q) = %d, exceeds Telegram's %d bytes", data, len(data), maxCallbackData)
+	}
+	for action := range callbackActions {
+		if strings.Contains(string(action), callbackSeparator) {
+			t.Errorf("action %q contains the separator", action)
+		}
+	}
+}
+
+func TestCallbackWithoutMessage(t *testing.T) {
+	s, telegram := newTestService(t)
+
+	err := s.handleCallbackQuery(context.Background(), &tgbotapi.CallbackQuery{
+		ID:              "q1",
+		From:            &tgbotapi.User{ID: 7},
+		InlineMessageID: "inline-1",
+		Data:            s.callbacks.encode(actionHome, ""),
+	})
+	if err != nil {
+		t.Fatalf("handleCallbackQuery() error = %v", err)
+	}
+	// Only the acknowledgement, which requests() leaves out
+	assertCalls(t, telegram.requests(), nil)
+	telegram.mu.Lock()
+	defer telegram.mu.Unlock()
+	if len(telegram.calls) != 1 || telegram.calls[0].method != "answerCallbackQuery" {
+		t.Errorf("calls = %v, want the callback acknowledged", telegram.calls)
+	}
+}
+
+func TestHandleUpdateRecoversPanic(t *testing.T) {
+	s, _ := newTestService(t)
+	// A nil train service makes the schedule lookup panic
+	s.trainService = nil
+	r := route{From: "3500", To: "4600"}
+	s.profile(7).setDefaultRoute(&r)
+
+	msg := groupMessage("/route")
+	msg.Chat = &tgbotapi.Chat{ID: 7, Type: "private"}
+	if err := s.handleUpdate(context.Background(), tgbotapi.Update{UpdateID: 1, Message: msg}); err == nil {
+		t.Error("handleUpdate() of a panicking handler = nil error")
+	}
+}
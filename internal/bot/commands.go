@@ -0,0 +1,348 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"rail-go/internal/logger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// defaultLanguage is used for users whose Telegram language has no translation
+const defaultLanguage = "he"
+
+// commandLanguages are the languages the command menu is registered in
+var commandLanguages = []string{"he", "en"}
+
+// commandArgs are the words following a command
+type commandArgs struct {
+	// raw is the text after the command, for commands taking free text
+	raw    string
+	fields []string
+}
+
+func parseCommandArgs(text string) commandArgs {
+	text = strings.TrimSpace(text)
+	return commandArgs{raw: text, fields: strings.Fields(text)}
+}
+
+// commandHandler runs a command once its arguments and permissions were checked
+type commandHandler func(ctx context.Context, msg *tgbotapi.Message, args commandArgs) error
+
+// command is a slash command the bot understands
+type command struct {
+	// name is the command without the leading slash
+	name string
+	// usage describes the arguments, e.g. "<run|pause|resume> <id>"
+	usage string
+	// description is the short text of the command menu, per language
+	description map[string]string
+	// help is the longer text shown by "/help <command>"
+	help    string
+	minArgs int
+	// maxArgs is -1 when the command takes free text
+	maxArgs int
+	admin   bool
+	handler commandHandler
+}
+
+// describe returns the menu text in the given language
+func (c *command) describe(language string) string {
+	if text, ok := c.description[language]; ok {
+		return text
+	}
+	return c.description[defaultLanguage]
+}
+
+func (c *command) usageText() string {
+	if c.usage == "" {
+		return "/" + c.name
+	}
+	return "/" + c.name + " " + c.usage
+}
+
+// commandRouter finds the command of a message
+type commandRouter struct {
+	commands []*command
+	byName   map[string]*command
+}
+
+func newCommandRouter(commands []*command) *commandRouter {
+	r := &commandRouter{commands: commands, byName: make(map[string]*command, len(commands))}
+	for _, cmd := range commands {
+		r.byName[cmd.name] = cmd
+	}
+	return r
+}
+
+func (r *commandRouter) lookup(name string) (*command, bool) {
+	cmd, ok := r.byName[strings.ToLower(name)]
+	return cmd, ok
+}
+
+// botCommands lists the commands of the menu in the given language
+func (r *commandRouter) botCommands(language string, admin bool) []tgbotapi.BotCommand {
+	var commands []tgbotapi.BotCommand
+	for _, cmd := range r.commands {
+		if cmd.admin && !admin {
+			continue
+		}
+		commands = append(commands, tgbotapi.BotCommand{
+			Command:     cmd.name,
+			Description: cmd.describe(language),
+		})
+	}
+	return commands
+}
+
+// botCommands defines the slash commands in the order of the command menu
+func (s *Service) botCommands() []*command {
+	return []*command{
+		{
+			name:        "start",
+			description: map[string]string{"he": "התחלה ותפריט ראשי", "en": "Start and show the main menu"},
+			help:        "מציג את התפריט הראשי.",
+			maxArgs:     1,
+			handler:     s.handleStartCommand,
+		},
+		{
+			name:        "help",
+			usage:       "[פקודה]",
+			description: map[string]string{"he": "עזרה ורשימת פקודות", "en": "Help and list of commands"},
+			help:        "מציג את רשימת הפקודות, או הסבר על פקודה אחת: /help alerts",
+			maxArgs:     1,
+			handler:     s.handleHelpCommand,
+		},
+		{
+			name:        "home",
+			description: map[string]string{"he": "רכבות הביתה", "en": "Trains home"},
+			help:        "מציג את הרכבות הקרובות במסלול הבית.",
+			handler: func(ctx context.Context, msg *tgbotapi.Message, _ commandArgs) error {
+				return s.handleHomeRoute(ctx, messageConversation(msg), 0)
+			},
+		},
+		{
+			name:        "work",
+			description: map[string]string{"he": "רכבות לעבודה", "en": "Trains to work"},
+			help:        "מציג את הרכבות הקרובות במסלול העבודה.",
+			handler: func(ctx context.Context, msg *tgbotapi.Message, _ commandArgs) error {
+				return s.handleWorkRoute(ctx, messageConversation(msg), 0)
+			},
+		},
+		{
+			name:        "other",
+			description: map[string]string{"he": "חיפוש מסלול אחר", "en": "Search another route"},
+			help:        "בחירת תחנת מוצא ותחנת יעד. אפשר גם לכתוב את המסלול ישירות, למשל: הרצליה השלום 08:30",
+			handler: func(ctx context.Context, msg *tgbotapi.Message, _ commandArgs) error {
+				return s.handleOtherRoute(ctx, messageConversation(msg), 0)
+			},
+		},
+		{
+			name:        "alerts",
+			description: map[string]string{"he": "שיבושים במסלולים שלך", "en": "Disruptions on your routes"},
+			help:        "מציג שיבושים ועבודות מתוכננות במסלולים שחיפשת.",
+			handler: func(ctx context.Context, msg *tgbotapi.Message, _ commandArgs) error {
+				return s.handleAlerts(ctx, msg)
+			},
+		},
+		{
+			name:        "route",
+			description: map[string]string{"he": "רכבות במסלול הקבוצה", "en": "Trains on the group's route"},
+			help:        "מציג את הרכבות הקרובות במסלול שנקבע עם /setroute. בקבוצה אפשר גם פשוט לתייג את הבוט.",
+			handler: func(ctx context.Context, msg *tgbotapi.Message, _ commandArgs) error {
+				return s.handleRouteCommand(ctx, msg)
+			},
+		},
+		{
+			name:        "setroute",
+			usage:       "<מוצא> <יעד>",
+			description: map[string]string{"he": "קביעת מסלול הקבוצה", "en": "Set the group's route"},
+			help:        "קובע את המסלול של /route, למשל: /setroute הרצליה השלום. בקבוצה רק מנהלי הקבוצה יכולים לשנות אותו. off מסיר את המסלול.",
+			minArgs:     1,
+			maxArgs:     -1,
+			handler: func(ctx context.Context, msg *tgbotapi.Message, args commandArgs) error {
+				return s.handleSetRouteCommand(ctx, msg, args.raw)
+			},
+		},
+		{
+			name:        "stats",
+			description: map[string]string{"he": "סטטיסטיקת שימוש", "en": "Usage statistics"},
+			help:        "זמן פעילות, מספר משתמשים, הודעות ושגיאות.",
+			admin:       true,
+			handler: func(ctx context.Context, msg *tgbotapi.Message, _ commandArgs) error {
+				return s.handleStats(ctx, msg)
+			},
+		},
+		{
+			name:        "broadcast",
+			usage:       "<הודעה>",
+			description: map[string]string{"he": "הודעה לכל המשתמשים", "en": "Message all known users"},
+			help:        "שולח את ההודעה לכל הצ'אטים המוכרים.",
+			minArgs:     1,
+			maxArgs:     -1,
+			admin:       true,
+			handler: func(ctx context.Context, msg *tgbotapi.Message, args commandArgs) error {
+				return s.handleBroadcast(ctx, msg, args.raw)
+			},
+		},
+		{
+			name:        "reload",
+			description: map[string]string{"he": "טעינה מחדש של ההגדרות", "en": "Reload configuration"},
+			help:        "טוען מחדש את ההגדרות ואת רשימת התחנות.",
+			admin:       true,
+			handler: func(ctx context.Context, msg *tgbotapi.Message, _ commandArgs) error {
+				return s.handleReload(ctx, msg)
+			},
+		},
+		{
+			name:        "flushcache",
+			description: map[string]string{"he": "ניקוי המטמון", "en": "Clear cached schedules"},
+			help:        "מוחק את לוחות הזמנים השמורים במטמון.",
+			admin:       true,
+			handler: func(ctx context.Context, msg *tgbotapi.Message, _ commandArgs) error {
+				count := s.trainService.FlushCache()
+				return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("%s נמחקו %d רשומות מהמטמון.", successEmoji, count))
+			},
+		},
+		{
+			name:        "debug",
+			usage:       "[on|off]",
+			description: map[string]string{"he": "מצב דיבאג", "en": "Toggle debug logging"},
+			help:        "מפעיל או מכבה את רישום הדיבאג בלוג.",
+			maxArgs:     1,
+			admin:       true,
+			handler: func(ctx context.Context, msg *tgbotapi.Message, args commandArgs) error {
+				return s.handleDebug(ctx, msg, args.raw)
+			},
+		},
+		{
+			name:        "loglevel",
+			usage:       "[level]",
+			description: map[string]string{"he": "רמת הלוג", "en": "Show or change the log level"},
+			help:        "מציג את רמת הלוג, או משנה אותה: /loglevel debug",
+			maxArgs:     1,
+			admin:       true,
+			handler: func(ctx context.Context, msg *tgbotapi.Message, args commandArgs) error {
+				return s.handleLogLevel(ctx, msg, args.raw)
+			},
+		},
+		{
+			name:        "tasks",
+			description: map[string]string{"he": "משימות מתוזמנות", "en": "Scheduled tasks"},
+			help:        "מציג את המשימות המתוזמנות עם הריצה האחרונה והבאה.",
+			admin:       true,
+			handler: func(ctx context.Context, msg *tgbotapi.Message, _ commandArgs) error {
+				return s.handleTasks(ctx, msg)
+			},
+		},
+		{
+			name:        "task",
+			usage:       "<run|pause|resume> <id>",
+			description: map[string]string{"he": "הרצה, השהיה או חידוש של משימה", "en": "Run, pause or resume a task"},
+			help:        "מריץ מיד, משהה או מחדש משימה מתוזמנת לפי המזהה שלה ב-/tasks.",
+			minArgs:     2,
+			maxArgs:     2,
+			admin:       true,
+			handler: func(ctx context.Context, msg *tgbotapi.Message, args commandArgs) error {
+				return s.handleTaskCommand(ctx, msg, args.fields[0], args.fields[1])
+			},
+		},
+	}
+}
+
+// handleCommand checks the permissions and arguments of a slash command and runs it
+func (s *Service) handleCommand(ctx context.Context, msg *tgbotapi.Message) error {
+	cmd, ok := s.commands.lookup(msg.Command())
+	if !ok {
+		logger.FromContext(ctx).Info("unknown command", "command", msg.Command())
+		return s.SendMessage(ctx, msg.Chat.ID,
+			fmt.Sprintf("%s פקודה לא ידועה. לרשימת הפקודות: /help", warningEmoji))
+	}
+	if cmd.admin && !s.isAdmin(msg.From.ID) {
+		return s.rejectNonAdmin(ctx, msg)
+	}
+
+	args := parseCommandArgs(msg.CommandArguments())
+	if len(args.fields) < cmd.minArgs || (cmd.maxArgs >= 0 && len(args.fields) > cmd.maxArgs) {
+		return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("שימוש: %s", cmd.usageText()))
+	}
+
+	logger.FromContext(ctx).Info("handling command",
+		"command", cmd.name,
+		"args", len(args.fields))
+
+	return cmd.handler(ctx, msg, args)
+}
+
+func (s *Service) handleStartCommand(ctx context.Context, msg *tgbotapi.Message, args commandArgs) error {
+	if args.raw == inlineHelpParameter {
+		return s.handleInlineHelp(ctx, msg)
+	}
+	return s.sendMainMenu(ctx, msg.Chat.ID,
+		fmt.Sprintf("%s ברוכים הבאים! בחרו מהתפריט למטה, או כתבו מסלול כמו \"הרצליה השלום 08:30\".\nלעזרה: /help", trainEmoji))
+}
+
+// handleHelpCommand lists the commands, or explains one of them
+func (s *Service) handleHelpCommand(ctx context.Context, msg *tgbotapi.Message, args commandArgs) error {
+	admin := s.isAdmin(msg.From.ID)
+
+	if len(args.fields) == 1 {
+		cmd, ok := s.commands.lookup(strings.TrimPrefix(args.fields[0], "/"))
+		if !ok || (cmd.admin && !admin) {
+			return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("%s פקודה לא ידועה.", warningEmoji))
+		}
+		return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("%s\n%s", cmd.usageText(), cmd.help))
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s פקודות:\n", trainEmoji)
+	for _, cmd := range s.commands.commands {
+		if cmd.admin {
+			continue
+		}
+		fmt.Fprintf(&text, "%s - %s\n", cmd.usageText(), cmd.describe(defaultLanguage))
+	}
+	if admin {
+		text.WriteString("\nפקודות מנהל:\n")
+		for _, cmd := range s.commands.commands {
+			if cmd.admin {
+				fmt.Fprintf(&text, "%s - %s\n", cmd.usageText(), cmd.describe(defaultLanguage))
+			}
+		}
+	}
+	text.WriteString("\nאפשר גם לכתוב מסלול ישירות, למשל: מהרצליה לתל אביב השלום ב-8")
+
+	return s.SendMessage(ctx, msg.Chat.ID, text.String())
+}
+
+// syncCommands registers the command menu with Telegram in every supported
+// language. Admins also see the admin commands in their private chat.
+func (s *Service) syncCommands() error {
+	s.mu.RLock()
+	adminIDs := append([]int64(nil), s.config.Bot.AdminIDs...)
+	s.mu.RUnlock()
+
+	// The menu without a language code is shown to users of any other language
+	var requests []tgbotapi.SetMyCommandsConfig
+	for _, language := range append([]string{""}, commandLanguages...) {
+		requests = append(requests, tgbotapi.NewSetMyCommandsWithScopeAndLanguage(tgbotapi.NewBotCommandScopeDefault(),
+			language, s.commands.botCommands(language, false)...))
+		for _, id := range adminIDs {
+			requests = append(requests, tgbotapi.NewSetMyCommandsWithScopeAndLanguage(tgbotapi.NewBotCommandScopeChat(id),
+				language, s.commands.botCommands(language, true)...))
+		}
+	}
+
+	for _, request := range requests {
+		if _, err := s.bot.Request(request); err != nil {
+			return fmt.Errorf("failed to set bot commands: %w", err)
+		}
+	}
+	s.logger.Info("bot commands registered",
+		"commands", len(s.commands.commands),
+		"languages", len(commandLanguages),
+		"admins", len(adminIDs))
+	return nil
+}
@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestBotCommands(t *testing.T) {
+	router := newCommandRouter((&Service{}).botCommands())
+	validName := regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+	for _, cmd := range router.commands {
+		if !validName.MatchString(cmd.name) {
+			t.Errorf("command %q: Telegram rejects this name", cmd.name)
+		}
+		for _, language := range commandLanguages {
+			if description := cmd.describe(language); description == "" || len(description) > 256 {
+				t.Errorf("command %q: invalid %s description %q", cmd.name, language, description)
+			}
+		}
+		if cmd.help == "" {
+			t.Errorf("command %q: missing help text", cmd.name)
+		}
+		if cmd.handler == nil {
+			t.Errorf("command %q: missing handler", cmd.name)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		language string
+		admin    bool
+		want     string
+		hidden   string
+	}{
+		{name: "Hebrew", language: "he", want: "home", hidden: "stats"},
+		{name: "English", language: "en", want: "alerts", hidden: "broadcast"},
+		{name: "Unknown language falls back", language: "fr", want: "start"},
+		{name: "Admin", language: "he", admin: true, want: "task"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found := false
+			for _, cmd := range router.botCommands(tt.language, tt.admin) {
+				if cmd.Command == tt.hidden {
+					t.Errorf("botCommands(%q, %v) lists %q", tt.language, tt.admin, tt.hidden)
+				}
+				if cmd.Command == tt.want {
+					found = true
+					want, _ := router.lookup(tt.want)
+					if cmd.Description != want.describe(tt.language) {
+						t.Errorf("description = %q, want %q", cmd.Description, want.describe(tt.language))
+					}
+				}
+			}
+			if !found {
+				t.Errorf("botCommands(%q, %v) misses %q", tt.language, tt.admin, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandLookup(t *testing.T) {
+	router := newCommandRouter((&Service{}).botCommands())
+
+	tests := []struct {
+		name  string
+		found bool
+	}{
+		{name: "help", found: true},
+		{name: "HELP", found: true},
+		{name: "task", found: true},
+		{name: "nosuchcommand", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := router.lookup(tt.name); ok != tt.found {
+				t.Errorf("lookup(%q) found = %v, want %v", tt.name, ok, tt.found)
+			}
+		})
+	}
+}
+
+func TestParseCommandArgs(t *testing.T) {
+	args := parseCommandArgs("  run   daily-report ")
+	if args.raw != "run   daily-report" {
+		t.Errorf("raw = %q", args.raw)
+	}
+	if len(args.fields) != 2 || args.fields[0] != "run" || args.fields[1] != "daily-report" {
+		t.Errorf("fields = %q", args.fields)
+	}
+	if args := parseCommandArgs(""); len(args.fields) != 0 {
+		t.Errorf("fields of empty arguments = %q", args.fields)
+	}
+}
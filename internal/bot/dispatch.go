@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// dispatcher handles the updates of each chat in order, in a goroutine per busy
+// chat. A chat whose replies wait for its send budget, like a busy group, then
+// holds up only its own updates and not those of every other chat.
+type dispatcher struct {
+	mu sync.Mutex
+	// pending holds the updates waiting for the goroutine of their chat; a chat
+	// is in the map while its goroutine runs
+	pending map[int64][]func()
+	wg      sync.WaitGroup
+}
+
+func newDispatcher() *dispatcher {
+	return &dispatcher{pending: make(map[int64][]func())}
+}
+
+// dispatch runs handle after the updates of the chat dispatched before it
+func (d *dispatcher) dispatch(chatID int64, handle func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if queue, busy := d.pending[chatID]; busy {
+		d.pending[chatID] = append(queue, handle)
+		return
+	}
+	d.pending[chatID] = nil
+	d.wg.Add(1)
+	go d.run(chatID, handle)
+}
+
+func (d *dispatcher) run(chatID int64, handle func()) {
+	defer d.wg.Done()
+	for {
+		handle()
+
+		d.mu.Lock()
+		queue := d.pending[chatID]
+		if len(queue) == 0 {
+			delete(d.pending, chatID)
+			d.mu.Unlock()
+			return
+		}
+		handle, d.pending[chatID] = queue[0], queue[1:]
+		d.mu.Unlock()
+	}
+}
+
+// wait waits until all dispatched updates have been handled
+func (d *dispatcher) wait() {
+	d.wg.Wait()
+}
+
+// updateChatID returns the chat an update belongs to. Inline queries and
+// buttons of inline results have no chat and go by their user.
+func updateChatID(update tgbotapi.Update) int64 {
+	if chat := update.FromChat(); chat != nil {
+		return chat.ID
+	}
+	if user := update.SentFrom(); user != nil {
+		return user.ID
+	}
+	return 0
+}
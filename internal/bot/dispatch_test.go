@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDispatcher(t *testing.T) {
+	d := newDispatcher()
+	release := make(chan struct{})
+
+	var mu sync.Mutex
+	var handled []int
+	record := func(n int) func() {
+		return func() {
+			mu.Lock()
+			handled = append(handled, n)
+			mu.Unlock()
+		}
+	}
+
+	// Chat 1 is stuck, e.g. waiting for its send budget
+	d.dispatch(1, func() { <-release })
+	d.dispatch(1, record(1))
+	d.dispatch(1, record(2))
+
+	otherChat := make(chan struct{})
+	d.dispatch(2, func() { close(otherChat) })
+	select {
+	case <-otherChat:
+	case <-time.After(time.Second):
+		t.Fatal("other chat held up by a stuck chat")
+	}
+
+	close(release)
+	d.wait()
+	if want := []int{1, 2}; !slices.Equal(handled, want) {
+		t.Errorf("handled %v, want %v", handled, want)
+	}
+	if len(d.pending) != 0 {
+		t.Errorf("pending chats after wait() = %d, want 0", len(d.pending))
+	}
+}
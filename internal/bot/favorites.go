@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"rail-go/internal/logger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// encodeRoute writes a route compactly for callback data as "<from>-<to>"
+func encodeRoute(r route) string {
+	return r.From + "-" + r.To
+}
+
+// decodeRoute reads a route written by encodeRoute
+func decodeRoute(data string) (route, bool) {
+	from, to, ok := strings.Cut(data, "-")
+	if !ok || from == "" || to == "" || strings.Contains(to, "-") {
+		return route{}, false
+	}
+	return route{From: from, To: to}, true
+}
+
+// sendFavorites offers the starred routes followed by the recent searches
+func (s *Service) sendFavorites(ctx context.Context, chatID int64) error {
+	keyboard, ok := s.favoritesKeyboard(chatID)
+	if !ok {
+		return s.SendMessage(ctx, chatID,
+			fmt.Sprintf("%s עוד אין מסלולים שמורים. מסלולים שתחפשו יופיעו כאן, ואפשר לסמן אותם בכוכב.", menuFavoritesLabel))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("%s בחרו מסלול:", menuFavoritesLabel))
+	msg.ReplyMarkup = keyboard
+	_, err := s.send(ctx, chatID, msg)
+	return err
+}
+
+// favoritesKeyboard lists each route with a button searching it and one starring
+// or unstarring it. It reports false when the chat has no routes.
+func (s *Service) favoritesKeyboard(chatID int64) (tgbotapi.InlineKeyboardMarkup, bool) {
+	p := s.profile(chatID)
+	favorites := p.favoriteRoutes()
+	var recent []route
+	for _, r := range p.savedRoutes() {
+		if !p.isFavorite(r) {
+			recent = append(recent, r)
+		}
+	}
+
+	keyboard := newKeyboard()
+	for _, r := range favorites {
+		keyboard.buttons(
+			fmt.Sprintf("⭐ %s", s.routeName(r)), s.callbacks.encode(actionRoute, encodeRoute(r)),
+			"✖️", s.callbacks.encode(actionFavorite, encodeRoute(r)))
+	}
+	for _, r := range recent {
+		keyboard.buttons(
+			fmt.Sprintf("🕘 %s", s.routeName(r)), s.callbacks.encode(actionRoute, encodeRoute(r)),
+			"⭐", s.callbacks.encode(actionFavorite, encodeRoute(r)))
+	}
+	return keyboard.inlineMarkup(), len(favorites)+len(recent) > 0
+}
+
+func (s *Service) routeName(r route) string {
+	return fmt.Sprintf("%s ← %s", s.trainService.GetStationName(r.From), s.trainService.GetStationName(r.To))
+}
+
+// handleRouteButton searches a route picked from the favorites keyboard
+func (s *Service) handleRouteButton(ctx context.Context, query *tgbotapi.CallbackQuery, payload string) error {
+	chatID := query.Message.Chat.ID
+	r, ok := decodeRoute(payload)
+	if !ok {
+		return s.SendMessage(ctx, chatID, fmt.Sprintf("%s מסלול לא תקין. אנא נסה שוב.", warningEmoji))
+	}
+
+	logger.FromContext(ctx).Info("handling saved route request",
+		"from", r.From,
+		"to", r.To)
+
+	// The favorites list stays, the schedule comes in a new message
+	return s.showSchedule(ctx, queryConversation(query), 0, r.From, r.To, time.Time{})
+}
+
+// handleFavoriteButton stars or unstars a route and updates the pressed button
+func (s *Service) handleFavoriteButton(ctx context.Context, query *tgbotapi.CallbackQuery, payload string) error {
+	chatID := query.Message.Chat.ID
+	r, ok := decodeRoute(payload)
+	if !ok {
+		return s.SendMessage(ctx, chatID, fmt.Sprintf("%s מסלול לא תקין. אנא נסה שוב.", warningEmoji))
+	}
+
+	favorite, ok := s.profile(chatID).toggleFavorite(r)
+	if !ok {
+		return s.SendMessage(ctx, chatID,
+			fmt.Sprintf("%s אפשר לשמור עד %d מועדפים. הסירו מסלול דרך %s.", warningEmoji, maxFavorites, menuFavoritesLabel))
+	}
+
+	logger.FromContext(ctx).Info("favorite route toggled",
+		"from", r.From,
+		"to", r.To,
+		"favorite", favorite)
+
+	// The star is either in the favorites list or under a schedule
+	markup := s.resultKeyboard(chatID, r, s.laterOf(query.Message.ReplyMarkup))
+	if strings.HasPrefix(query.Message.Text, menuFavoritesLabel) {
+		markup, _ = s.favoritesKeyboard(chatID)
+	}
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatID, query.Message.MessageID, markup)
+	if err := s.request(ctx, chatID, edit); err != nil {
+		return fmt.Errorf("failed to update favorite buttons: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDecodeRoute(t *testing.T) {
+	tests := []struct {
+		data string
+		want route
+		ok   bool
+	}{
+		{data: "4600-8700", want: route{From: "4600", To: "8700"}, ok: true},
+		{data: encodeRoute(route{From: "300", To: "5000"}), want: route{From: "300", To: "5000"}, ok: true},
+		{data: "4600", ok: false},
+		{data: "-8700", ok: false},
+		{data: "4600-", ok: false},
+		{data: "4600-8700-1", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			got, ok := decodeRoute(tt.data)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("decodeRoute(%q) = %v, %v, want %v, %v", tt.data, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestToggleFavorite(t *testing.T) {
+	p := newStateService().profile(1)
+	r := route{From: "4600", To: "8700"}
+
+	if favorite, ok := p.toggleFavorite(r); !favorite || !ok {
+		t.Fatalf("toggleFavorite() = %v, %v, want starred", favorite, ok)
+	}
+	if !p.isFavorite(r) {
+		t.Errorf("isFavorite() = false after starring")
+	}
+	if favorite, ok := p.toggleFavorite(r); favorite || !ok {
+		t.Fatalf("toggleFavorite() = %v, %v, want unstarred", favorite, ok)
+	}
+	if p.isFavorite(r) {
+		t.Errorf("isFavorite() = true after unstarring")
+	}
+
+	for i := 0; i < maxFavorites; i++ {
+		p.toggleFavorite(route{From: fmt.Sprint(i), To: "8700"})
+	}
+	if _, ok := p.toggleFavorite(r); ok {
+		t.Errorf("toggleFavorite() beyond %d favorites succeeded", maxFavorites)
+	}
+	if got := len(p.favoriteRoutes()); got != maxFavorites {
+		t.Errorf("len(favoriteRoutes()) = %d, want %d", got, maxFavorites)
+	}
+}
@@ -0,0 +1,227 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"rail-go/internal/logger"
+	"rail-go/internal/train"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// conversation is the flow of one user in one chat. A private chat has a single
+// conversation; in groups every member has their own, so that members picking
+// stations at the same time do not overwrite each other's selection.
+type conversation struct {
+	chatID int64
+	// userID is 0 in private chats, which keeps their keys as they were before
+	// groups were supported
+	userID int64
+	// name is the first name of the group member, used to address them
+	name string
+}
+
+func conversationOf(chat *tgbotapi.Chat, user *tgbotapi.User) conversation {
+	c := conversation{chatID: chat.ID}
+	if user != nil && !chat.IsPrivate() {
+		c.userID = user.ID
+		c.name = user.FirstName
+	}
+	return c
+}
+
+func messageConversation(msg *tgbotapi.Message) conversation {
+	return conversationOf(msg.Chat, msg.From)
+}
+
+func queryConversation(query *tgbotapi.CallbackQuery) conversation {
+	return conversationOf(query.Message.Chat, query.From)
+}
+
+// stateKey is the userState key of the conversation state
+func (c conversation) stateKey() string {
+	return c.key("state")
+}
+
+// routeKey is the userState key of the route being selected
+func (c conversation) routeKey() string {
+	return c.key("route")
+}
+
+func (c conversation) key(kind string) string {
+	if c.userID == 0 {
+		return fmt.Sprintf("%s_%d", kind, c.chatID)
+	}
+	return fmt.Sprintf("%s_%d_%d", kind, c.chatID, c.userID)
+}
+
+// inGroup reports whether the conversation belongs to a group member
+func (c conversation) inGroup() bool {
+	return c.userID != 0
+}
+
+// askForText asks the user to type something, like the first letters of a
+// station, in the message with messageID or in a new message when it is 0.
+// With privacy mode the bot only sees the group messages replying to it, so in
+// groups the question is a new message mentioning the member and forcing a reply.
+func (s *Service) askForText(ctx context.Context, conv conversation, messageID int, text string) error {
+	if !conv.inGroup() {
+		_, err := s.sendOrEdit(ctx, conv.chatID, messageID, text, nil)
+		return err
+	}
+
+	msg := tgbotapi.NewMessage(conv.chatID, text)
+	if conv.name != "" {
+		msg.Text = conv.name + " " + text
+		msg.Entities = []tgbotapi.MessageEntity{{
+			Type:   "text_mention",
+			Length: len(utf16.Encode([]rune(conv.name))),
+			User:   &tgbotapi.User{ID: conv.userID, FirstName: conv.name},
+		}}
+	}
+	msg.ReplyMarkup = tgbotapi.ForceReply{ForceReply: true, Selective: true}
+	if _, err := s.send(ctx, conv.chatID, msg); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return nil
+}
+
+// textStates are the conversation states waiting for the user to type
+var textStates = map[string]bool{
+	"awaitingFromStation": true,
+	"awaitingToStation":   true,
+}
+
+// commandForBot reports whether a command is meant for this bot. In groups
+// with several bots a command may be addressed to another one as /cmd@otherbot,
+// and a bare command the bot does not know is taken to be another bot's.
+func (s *Service) commandForBot(msg *tgbotapi.Message) bool {
+	_, bot, addressed := strings.Cut(msg.CommandWithAt(), "@")
+	if addressed {
+		return strings.EqualFold(bot, s.bot.Self.UserName)
+	}
+	_, known := s.commands.lookup(msg.Command())
+	return known
+}
+
+// groupText returns the text of a group message meant for the bot, without the
+// mention of the bot, and reports false for chatter between the members.
+// Replies to the bot, mentions, menu buttons and the station names of a member
+// the bot asked to type one are meant for the bot.
+func (s *Service) groupText(msg *tgbotapi.Message) (string, bool) {
+	if msg.Text == "" {
+		return "", false
+	}
+	if msg.ReplyToMessage != nil && msg.ReplyToMessage.From != nil && msg.ReplyToMessage.From.ID == s.bot.Self.ID {
+		return msg.Text, true
+	}
+	if s.mention != nil && slices.ContainsFunc(msg.Entities, func(e tgbotapi.MessageEntity) bool {
+		return e.Type == "mention"
+	}) {
+		// Entity offsets count UTF-16 code units, so look the mention up in the text
+		if s.mention.MatchString(msg.Text) {
+			return strings.TrimSpace(s.mention.ReplaceAllString(msg.Text, "")), true
+		}
+	}
+	state, _ := s.userState.Load(messageConversation(msg).stateKey())
+	if name, _ := state.(string); textStates[name] {
+		return msg.Text, true
+	}
+	return msg.Text, isMenuLabel(msg.Text)
+}
+
+// mentionPattern returns the pattern of a mention of the bot, or nil when the bot
+// has no username to be mentioned by
+func mentionPattern(userName string) *regexp.Regexp {
+	if userName == "" {
+		return nil
+	}
+	return regexp.MustCompile(`(?i)@` + regexp.QuoteMeta(userName) + `\b`)
+}
+
+// addressedToBot filters the messages of group chats down to the ones meant for
+// the bot. It removes the mention of the bot from the text of msg, so handlers
+// see "@rail_bot הרצליה השלום" as "הרצליה השלום". Messages of private chats are
+// always meant for the bot.
+func (s *Service) addressedToBot(ctx context.Context, msg *tgbotapi.Message) bool {
+	if msg.Chat.IsPrivate() {
+		return true
+	}
+	if msg.IsCommand() {
+		if !s.commandForBot(msg) {
+			logger.FromContext(ctx).Debug("command for another bot ignored", "command", msg.CommandWithAt())
+			return false
+		}
+		return true
+	}
+
+	text, ok := s.groupText(msg)
+	if !ok {
+		logger.FromContext(ctx).Debug("group message not meant for the bot ignored")
+		return false
+	}
+	msg.Text = text
+	return true
+}
+
+// handleGroupMention answers a bare mention of the bot with the route of the
+// group, or with the route menu when the group has none
+func (s *Service) handleGroupMention(ctx context.Context, msg *tgbotapi.Message) error {
+	if r, ok := s.profile(msg.Chat.ID).defaultRoute(); ok {
+		return s.showSchedule(ctx, messageConversation(msg), 0, r.From, r.To, time.Time{})
+	}
+	return s.sendRouteMenu(ctx, msg.Chat.ID, fmt.Sprintf("%s בחר יעד:", trainEmoji))
+}
+
+// handleRouteCommand shows the trains on the route of the chat
+func (s *Service) handleRouteCommand(ctx context.Context, msg *tgbotapi.Message) error {
+	r, ok := s.profile(msg.Chat.ID).defaultRoute()
+	if !ok {
+		return s.SendMessage(ctx, msg.Chat.ID,
+			fmt.Sprintf("%s עוד לא נקבע מסלול. אפשר לקבוע אותו כך: /setroute הרצליה השלום", warningEmoji))
+	}
+	return s.showSchedule(ctx, messageConversation(msg), 0, r.From, r.To, time.Time{})
+}
+
+// handleSetRouteCommand sets or removes the route of the chat. In groups only
+// the administrators of the group may change it.
+func (s *Service) handleSetRouteCommand(ctx context.Context, msg *tgbotapi.Message, text string) error {
+	if !msg.Chat.IsPrivate() && !s.isAdmin(msg.From.ID) {
+		member, err := s.bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+			ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: msg.Chat.ID, UserID: msg.From.ID},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to check group administrator: %w", err)
+		}
+		if !member.IsCreator() && !member.IsAdministrator() {
+			return s.SendMessage(ctx, msg.Chat.ID,
+				fmt.Sprintf("%s רק מנהלי הקבוצה יכולים לשנות את המסלול שלה.", warningEmoji))
+		}
+	}
+
+	p := s.profile(msg.Chat.ID)
+	if text == "off" {
+		p.setDefaultRoute(nil)
+		logger.FromContext(ctx).Info("default route removed")
+		return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("%s המסלול הוסר.", successEmoji))
+	}
+
+	from, to, err := train.ParseRoute(text)
+	if err != nil {
+		logger.FromContext(ctx).Info("default route not recognized", "error", err)
+		return s.SendMessage(ctx, msg.Chat.ID,
+			fmt.Sprintf("%s לא זיהיתי את המסלול. נסה למשל: /setroute הרצליה השלום", warningEmoji))
+	}
+	r := route{From: from, To: to}
+	p.setDefaultRoute(&r)
+
+	logger.FromContext(ctx).Info("default route set", "from", from, "to", to)
+	return s.SendMessage(ctx, msg.Chat.ID,
+		fmt.Sprintf("%s המסלול נקבע: %s. להצגת הרכבות: /route", successEmoji, s.routeName(r)))
+}
@@ -0,0 +1,269 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestConversationKeys(t *testing.T) {
+	private := &tgbotapi.Chat{ID: 7, Type: "private"}
+	group := &tgbotapi.Chat{ID: -100, Type: "supergroup"}
+	alice := &tgbotapi.User{ID: 7, FirstName: "Alice"}
+	bob := &tgbotapi.User{ID: 8, FirstName: "Bob"}
+
+	tests := []struct {
+		name  string
+		conv  conversation
+		state string
+		route string
+	}{
+		{name: "Private chat", conv: conversationOf(private, alice), state: "state_7", route: "route_7"},
+		{name: "Group member", conv: conversationOf(group, alice), state: "state_-100_7", route: "route_-100_7"},
+		{name: "Other group member", conv: conversationOf(group, bob), state: "state_-100_8", route: "route_-100_8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.conv.stateKey(); got != tt.state {
+				t.Errorf("stateKey() = %q, want %q", got, tt.state)
+			}
+			if got := tt.conv.routeKey(); got != tt.route {
+				t.Errorf("routeKey() = %q, want %q", got, tt.route)
+			}
+		})
+	}
+}
+
+// groupMessage returns a message of user 7 in a group. A text starting with "/"
+// is a command.
+func groupMessage(text string) *tgbotapi.Message {
+	msg := &tgbotapi.Message{
+		MessageID: 10,
+		Chat:      &tgbotapi.Chat{ID: -100, Type: "group"},
+		From:      &tgbotapi.User{ID: 7, FirstName: "Alice"},
+		Text:      text,
+	}
+	if len(text) > 0 && text[0] == '/' {
+		length := len(text)
+		for i, r := range text {
+			if r == ' ' {
+				length = i
+				break
+			}
+		}
+		msg.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Length: length}}
+	}
+	return msg
+}
+
+func TestAddressedToBot(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      func() *tgbotapi.Message
+		state    string
+		want     bool
+		wantText string
+	}{
+		{
+			name: "Private chat",
+			msg: func() *tgbotapi.Message {
+				msg := groupMessage("שלום")
+				msg.Chat = &tgbotapi.Chat{ID: 7, Type: "private"}
+				return msg
+			},
+			want:     true,
+			wantText: "שלום",
+		},
+		{
+			name: "Group chatter",
+			msg:  func() *tgbotapi.Message { return groupMessage("נפגשים בתחנה?") },
+		},
+		{
+			name: "Mention",
+			msg: func() *tgbotapi.Message {
+				msg := groupMessage("@Rail_Bot הרצליה השלום")
+				msg.Entities = []tgbotapi.MessageEntity{{Type: "mention", Length: 9}}
+				return msg
+			},
+			want:     true,
+			wantText: "הרצליה השלום",
+		},
+		{
+			name: "Mention of another user",
+			msg: func() *tgbotapi.Message {
+				msg := groupMessage("@rail_bot_fan הרצליה")
+				msg.Entities = []tgbotapi.MessageEntity{{Type: "mention", Length: 13}}
+				return msg
+			},
+		},
+		{
+			name: "Reply to the bot",
+			msg: func() *tgbotapi.Message {
+				msg := groupMessage("הרצליה")
+				msg.ReplyToMessage = &tgbotapi.Message{From: &tgbotapi.User{ID: 1}}
+				return msg
+			},
+			want:     true,
+			wantText: "הרצליה",
+		},
+		{
+			name:     "Member in the middle of a route selection",
+			msg:      func() *tgbotapi.Message { return groupMessage("הרצ") },
+			state:    "awaitingFromStation",
+			want:     true,
+			wantText: "הרצ",
+		},
+		{
+			name:  "Member choosing a station from the buttons",
+			msg:   func() *tgbotapi.Message { return groupMessage("נפגשים בתחנה?") },
+			state: "awaitingToStationSelection",
+		},
+		{
+			name:     "Menu button",
+			msg:      func() *tgbotapi.Message { return groupMessage(menuFavoritesLabel) },
+			want:     true,
+			wantText: menuFavoritesLabel,
+		},
+		{
+			name: "Command",
+			msg:  func() *tgbotapi.Message { return groupMessage("/help") },
+			want: true,
+		},
+		{
+			name: "Command for this bot",
+			msg:  func() *tgbotapi.Message { return groupMessage("/help@rail_bot") },
+			want: true,
+		},
+		{
+			name: "Command for another bot",
+			msg:  func() *tgbotapi.Message { return groupMessage("/help@other_bot") },
+		},
+		{
+			name: "Unknown command",
+			msg:  func() *tgbotapi.Message { return groupMessage("/weather") },
+		},
+		{
+			name: "Unknown command for this bot",
+			msg:  func() *tgbotapi.Message { return groupMessage("/weather@rail_bot") },
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestService(t)
+			msg := tt.msg()
+			if tt.state != "" {
+				s.userState.Store(messageConversation(msg).stateKey(), tt.state)
+			}
+			// Another member's flow does not make chatter meant for the bot
+			s.userState.Store(conversation{chatID: -100, userID: 8}.stateKey(), "awaitingFromStation")
+
+			if got := s.addressedToBot(context.Background(), msg); got != tt.want {
+				t.Fatalf("addressedToBot() = %v, want %v", got, tt.want)
+			}
+			if tt.wantText != "" && msg.Text != tt.wantText {
+				t.Errorf("text = %q, want %q", msg.Text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestGroupRouteSelection(t *testing.T) {
+	s, telegram := newTestService(t)
+	ctx := context.Background()
+
+	if err := s.handleMessage(ctx, groupMessage("/other")); err != nil {
+		t.Fatalf("handleMessage() error = %v", err)
+	}
+	bob := conversation{chatID: -100, userID: 8, name: "Bob"}
+	if err := s.handleOtherRoute(ctx, bob, 0); err != nil {
+		t.Fatalf("handleOtherRoute() error = %v", err)
+	}
+
+	for _, conv := range []conversation{{chatID: -100, userID: 7}, bob} {
+		if state, _ := s.userState.Load(conv.stateKey()); state != "awaitingFromStation" {
+			t.Errorf("state of user %d = %v, want awaitingFromStation", conv.userID, state)
+		}
+	}
+	if _, ok := s.userState.Load("state_-100"); ok {
+		t.Error("group chat has a shared state")
+	}
+
+	assertCalls(t, telegram.requests(), []telegramCall{
+		{method: "sendMessage", params: map[string][]string{"text": {"Alice"}, "reply_markup": {`"force_reply":true`}, "entities": {`"id":7`}}},
+		{method: "sendMessage", params: map[string][]string{"text": {"Bob"}, "reply_markup": {`"selective":true`}, "entities": {`"id":8`}}},
+	})
+}
+
+func TestGroupSearchEndsFlow(t *testing.T) {
+	s, _ := newTestService(t)
+	alice := conversation{chatID: -100, userID: 7}
+	s.userState.Store(alice.stateKey(), "awaitingToStationSelection")
+	s.userState.Store(alice.routeKey(), map[string]string{"from": "3500", "to": "4600"})
+
+	err := s.handleCallbackQuery(context.Background(), &tgbotapi.CallbackQuery{
+		ID:      "q1",
+		From:    &tgbotapi.User{ID: 7, FirstName: "Alice"},
+		Data:    s.callbacks.encode(actionSearch, ""),
+		Message: &tgbotapi.Message{MessageID: 5, Chat: &tgbotapi.Chat{ID: -100, Type: "group"}},
+	})
+	if err != nil {
+		t.Fatalf("handleCallbackQuery() error = %v", err)
+	}
+	if state, ok := s.userState.Load(alice.stateKey()); ok {
+		t.Errorf("state after the search = %v, want none", state)
+	}
+	if _, ok := s.groupText(groupMessage("נפגשים בתחנה?")); ok {
+		t.Error("chatter after the search is meant for the bot")
+	}
+}
+
+func TestSetRouteCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		msg       func() *tgbotapi.Message
+		wantRoute bool
+		want      []telegramCall
+	}{
+		{
+			name: "Private chat",
+			msg: func() *tgbotapi.Message {
+				msg := groupMessage("/setroute הרצליה השלום")
+				msg.Chat = &tgbotapi.Chat{ID: 7, Type: "private"}
+				return msg
+			},
+			wantRoute: true,
+			want:      []telegramCall{{method: "sendMessage", params: map[string][]string{"text": {"/route"}}}},
+		},
+		{
+			name: "Group member who is not an administrator",
+			msg:  func() *tgbotapi.Message { return groupMessage("/setroute@rail_bot הרצליה השלום") },
+			want: []telegramCall{
+				{method: "getChatMember", params: map[string][]string{"user_id": {"7"}}},
+				{method: "sendMessage", params: map[string][]string{"text": {"מנהלי הקבוצה"}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, telegram := newTestService(t)
+			msg := tt.msg()
+			if err := s.handleMessage(context.Background(), msg); err != nil {
+				t.Fatalf("handleMessage() error = %v", err)
+			}
+
+			r, ok := s.profile(msg.Chat.ID).defaultRoute()
+			if ok != tt.wantRoute {
+				t.Fatalf("defaultRoute() set = %v, want %v", ok, tt.wantRoute)
+			}
+			if ok && r != (route{From: "3500", To: "4600"}) {
+				t.Errorf("defaultRoute() = %+v", r)
+			}
+			assertCalls(t, telegram.requests(), tt.want)
+		})
+	}
+}
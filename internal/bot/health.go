@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// telegramCheckInterval is how long a Telegram connectivity check result is reused
+const telegramCheckInterval = 30 * time.Second
+
+type telegramHealth struct {
+	err       error
+	checkedAt time.Time
+}
+
+// CheckTelegram reports whether the Telegram Bot API is reachable with our token.
+// The result is reused for a while; the lock is not held during the request.
+func (s *Service) CheckTelegram(ctx context.Context) error {
+	s.healthMu.Lock()
+	last := s.telegram
+	s.healthMu.Unlock()
+	if time.Since(last.checkedAt) < telegramCheckInterval {
+		return last.err
+	}
+
+	var err error
+	if getMeErr := s.getMe(ctx); getMeErr != nil {
+		err = fmt.Errorf("telegram unreachable: %w", getMeErr)
+	}
+	if ctx.Err() != nil {
+		// The caller gave up; that says nothing about Telegram
+		return err
+	}
+
+	s.healthMu.Lock()
+	s.telegram = telegramHealth{err: err, checkedAt: time.Now()}
+	s.healthMu.Unlock()
+	return err
+}
+
+// getMe calls getMe with ctx, which the requests of the Telegram library do not take.
+// Transport errors are returned without the request URL, which holds the bot token.
+func (s *Service) getMe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf(s.apiEndpoint, s.bot.Token, "getMe"), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.bot.Client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return errors.New("request failed")
+	}
+	defer resp.Body.Close()
+
+	var apiResp tgbotapi.APIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if !apiResp.Ok {
+		return errors.New(apiResp.Description)
+	}
+	return nil
+}
+
+// CheckRailAPI reports whether the last Israel Railways API call succeeded
+func (s *Service) CheckRailAPI(ctx context.Context) error {
+	return s.trainService.CheckHealth()
+}
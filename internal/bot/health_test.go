@@ -0,0 +1,44 @@
+package bot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckTelegram(t *testing.T) {
+	s, _ := newTestService(t)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.CheckTelegram(cancelled); err == nil {
+		t.Error("CheckTelegram() with a cancelled context = nil, want an error")
+	}
+	if !s.telegram.checkedAt.IsZero() {
+		t.Error("cancelled check cached as the Telegram health")
+	}
+
+	if err := s.CheckTelegram(context.Background()); err != nil {
+		t.Errorf("CheckTelegram() error = %v", err)
+	}
+	if s.telegram.checkedAt.IsZero() {
+		t.Error("check not cached")
+	}
+}
+
+func TestCheckTelegramHidesToken(t *testing.T) {
+	s, _ := newTestService(t)
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	s.apiEndpoint = server.URL + "/bot%s/%s"
+
+	err := s.CheckTelegram(context.Background())
+	if err == nil {
+		t.Fatal("CheckTelegram() of a closed server = nil, want an error")
+	}
+	if strings.Contains(err.Error(), s.bot.Token) {
+		t.Errorf("CheckTelegram() error %q shows the bot token", err)
+	}
+}
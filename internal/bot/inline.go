@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"rail-go/internal/logger"
+	"rail-go/internal/train"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// inlineCacheTime is how long Telegram may reuse the results for the same query, in seconds
+	inlineCacheTime = 60
+	maxInlineTrips  = 5
+	// inlineHelpParameter is the /start parameter of the "switch to private chat" button
+	inlineHelpParameter = "inline_help"
+)
+
+// handleInlineQuery answers "@bot <from> <to>" with the next trips, so a train
+// time can be shared in any chat
+func (s *Service) handleInlineQuery(ctx context.Context, query *tgbotapi.InlineQuery) error {
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID: query.ID,
+		CacheTime:     inlineCacheTime,
+		Results:       []interface{}{},
+	}
+
+	text := strings.TrimSpace(query.Query)
+	if text == "" {
+		answer.SwitchPMText = "הקלד תחנת מוצא ותחנת יעד, למשל: הרצליה השלום"
+		answer.SwitchPMParameter = inlineHelpParameter
+		return s.answerInline(answer)
+	}
+
+	from, to, err := train.ParseRoute(text)
+	if err != nil {
+		logger.FromContext(ctx).Info("inline query without a route", "error", err)
+		answer.SwitchPMText = "לא זוהו תחנות. נסה למשל: הרצליה השלום"
+		answer.SwitchPMParameter = inlineHelpParameter
+		return s.answerInline(answer)
+	}
+
+	schedule, err := s.trainService.PlanTrips(ctx, from, to, time.Now())
+	if errors.Is(err, train.ErrUnknownStation) {
+		logger.FromContext(ctx).Info("inline query with an unknown station", "error", err)
+		answer.SwitchPMText = "לא זוהו תחנות. נסה למשל: הרצליה השלום"
+		answer.SwitchPMParameter = inlineHelpParameter
+		return s.answerInline(answer)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get trips: %w", err)
+	}
+
+	trips := schedule.Trips
+	if len(trips) > maxInlineTrips {
+		trips = trips[:maxInlineTrips]
+	}
+	for i, trip := range trips {
+		answer.Results = append(answer.Results, inlineTripResult(fmt.Sprintf("%s-%s-%d", from, to, i), schedule, trip))
+	}
+	if len(trips) == 0 {
+		answer.SwitchPMText = "לא נמצאו רכבות במסלול זה בשעות הקרובות"
+		answer.SwitchPMParameter = inlineHelpParameter
+	}
+
+	logger.FromContext(ctx).Info("inline query answered",
+		"from", from,
+		"to", to,
+		"results", len(answer.Results))
+	return s.answerInline(answer)
+}
+
+// handleInlineHelp explains inline mode to users who tapped the help button
+func (s *Service) handleInlineHelp(ctx context.Context, msg *tgbotapi.Message) error {
+	return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf(
+		"%s אפשר לחפש רכבות מכל צ'אט ולשתף אותן.\n"+
+			"הקלד @%s ואחריו תחנת מוצא ותחנת יעד, למשל:\n"+
+			"@%s הרצליה השלום",
+		searchEmoji, s.bot.Self.UserName, s.bot.Self.UserName))
+}
+
+func (s *Service) answerInline(answer tgbotapi.InlineConfig) error {
+	if _, err := s.bot.Request(answer); err != nil {
+		return fmt.Errorf("failed to answer inline query: %w", err)
+	}
+	return nil
+}
+
+// inlineTripResult renders a trip as an article that sends a shareable message
+func inlineTripResult(id string, schedule train.Schedule, trip train.Trip) tgbotapi.InlineQueryResultArticle {
+	first := trip.Legs[0]
+	title := fmt.Sprintf("%s יציאה %s · הגעה %s (%d דק׳)", trainEmoji,
+		trip.Departure.Format("15:04"), trip.Arrival.Format("15:04"),
+		int(trip.Arrival.Sub(trip.Departure).Minutes()))
+
+	changes := "ללא החלפות"
+	if trip.Changes() > 0 {
+		changes = fmt.Sprintf("החלפות: %d", trip.Changes())
+	}
+	description := fmt.Sprintf("%s ← %s · רציף %d · %s",
+		schedule.From.Heb, schedule.To.Heb, first.FromPlatform, changes)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s ← %s\n", trainEmoji, schedule.From.Heb, schedule.To.Heb)
+	if schedule.NoService != nil {
+		fmt.Fprintf(&b, "%s %s\n", warningEmoji, train.NoServiceNotice(*schedule.NoService))
+	}
+	for _, leg := range trip.Legs {
+		fmt.Fprintf(&b, "%s יציאה: %s מ%s (רציף %d)\n", stationEmoji,
+			leg.Departure.Format("15:04"), leg.FromName, leg.FromPlatform)
+		fmt.Fprintf(&b, "%s הגעה: %s ל%s (רציף %d)\n", stationEmoji,
+			leg.Arrival.Format("15:04"), leg.ToName, leg.ToPlatform)
+	}
+
+	result := tgbotapi.NewInlineQueryResultArticle(id, title, strings.TrimSpace(b.String()))
+	result.Description = description
+	return result
+}
@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"rail-go/internal/train"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestInlineTripResult(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2025, 5, 4, hour, minute, 0, 0, time.UTC)
+	}
+	schedule := train.Schedule{
+		From: train.Station{ID: "3500", Heb: "הרצליה"},
+		To:   train.Station{ID: "4600", Heb: "תל אביב - השלום"},
+	}
+
+	tests := []struct {
+		name        string
+		trip        train.Trip
+		title       string
+		description string
+		text        []string
+	}{
+		{
+			name: "Direct",
+			trip: train.Trip{Departure: at(8, 5), Arrival: at(8, 25), Legs: []train.Leg{
+				{FromName: "הרצליה", FromPlatform: 1, ToName: "תל אביב - השלום", ToPlatform: 2, Departure: at(8, 5), Arrival: at(8, 25)},
+			}},
+			title:       "יציאה 08:05 · הגעה 08:25 (20 דק׳)",
+			description: "רציף 1 · ללא החלפות",
+			text:        []string{"יציאה: 08:05 מהרצליה (רציף 1)", "הגעה: 08:25 לתל אביב - השלום (רציף 2)"},
+		},
+		{
+			name: "With change",
+			trip: train.Trip{Departure: at(8, 20), Arrival: at(8, 47), Legs: []train.Leg{
+				{FromName: "הרצליה", FromPlatform: 2, ToName: "סבידור", Departure: at(8, 20), Arrival: at(8, 35)},
+				{FromName: "סבידור", ToName: "תל אביב - השלום", Departure: at(8, 42), Arrival: at(8, 47)},
+			}},
+			title:       "(27 דק׳)",
+			description: "החלפות: 1",
+			text:        []string{"יציאה: 08:42 מסבידור"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := inlineTripResult("id", schedule, tt.trip)
+			if !strings.Contains(result.Title, tt.title) {
+				t.Errorf("Title = %q, want it to contain %q", result.Title, tt.title)
+			}
+			if !strings.Contains(result.Description, tt.description) {
+				t.Errorf("Description = %q, want it to contain %q", result.Description, tt.description)
+			}
+			text := result.InputMessageContent.(tgbotapi.InputTextMessageContent).Text
+			for _, want := range tt.text {
+				if !strings.Contains(text, want) {
+					t.Errorf("message %q does not contain %q", text, want)
+				}
+			}
+		})
+	}
+}
+
+func TestInlineTripResultNoService(t *testing.T) {
+	resume := time.Date(2025, 5, 3, 20, 0, 0, 0, time.UTC) // Saturday
+	schedule := train.Schedule{
+		From:      train.Station{ID: "3500", Heb: "הרצליה"},
+		To:        train.Station{ID: "4600", Heb: "תל אביב - השלום"},
+		Notice:    "no service during שבת, showing trains from Sat 2025-05-03 20:00",
+		NoService: &train.ServiceWindow{End: resume, Name: "שבת"},
+	}
+	trip := train.Trip{Departure: resume.Add(5 * time.Minute), Arrival: resume.Add(25 * time.Minute), Legs: []train.Leg{
+		{FromName: "הרצליה", ToName: "תל אביב - השלום", Departure: resume.Add(5 * time.Minute), Arrival: resume.Add(25 * time.Minute)},
+	}}
+
+	text := inlineTripResult("id", schedule, trip).InputMessageContent.(tgbotapi.InputTextMessageContent).Text
+	if want := "אין שירות רכבות בשבת. אין רכבות עד יום שבת 20:00."; !strings.Contains(text, want) {
+		t.Errorf("message %q does not contain %q", text, want)
+	}
+	if strings.Contains(text, "no service") {
+		t.Errorf("message %q contains the English notice", text)
+	}
+}
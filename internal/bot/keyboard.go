@@ -1,19 +1,125 @@
 package bot
 
-import tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-
-var trainKeyBoard = tgbotapi.NewInlineKeyboardMarkup(
-	tgbotapi.NewInlineKeyboardRow(
-		tgbotapi.NewInlineKeyboardButtonData("home", "home"),
-		tgbotapi.NewInlineKeyboardButtonData("work", "work"),
-		tgbotapi.NewInlineKeyboardButtonData("other", "other"),
-	),
+import (
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-var trainStations = tgbotapi.NewInlineKeyboardMarkup(
-	tgbotapi.NewInlineKeyboardRow(
-		tgbotapi.NewInlineKeyboardButtonData("home", "home"),
-		tgbotapi.NewInlineKeyboardButtonData("work", "work"),
-		tgbotapi.NewInlineKeyboardButtonData("other", "other"),
-	),
+// Reply keyboard labels. The keyboard sends the label as a message, so these
+// are also the texts handleDefaultState reacts to.
+const (
+	menuSearchLabel    = trainEmoji + " חיפוש רכבת"
+	menuFavoritesLabel = "⭐ מועדפים"
+	menuAlertsLabel    = "🔔 שיבושים"
+	menuSettingsLabel  = "⚙️ הגדרות"
+
+	settingsAlertsOnLabel   = "📣 עדכונים אוטומטיים: פועל"
+	settingsAlertsOffLabel  = "📣 עדכונים אוטומטיים: כבוי"
+	settingsClearLabel      = "🗑 מחיקת מסלולים אחרונים"
+	settingsBackLabel       = "⬅️ חזרה לתפריט"
+	keyboardPlaceholderText = "כתבו מסלול, למשל: הרצליה השלום 08:30"
 )
+
+// isMenuLabel reports whether text was sent by a button of the reply keyboards
+func isMenuLabel(text string) bool {
+	switch text {
+	case menuSearchLabel, menuFavoritesLabel, menuAlertsLabel, menuSettingsLabel,
+		settingsAlertsOnLabel, settingsAlertsOffLabel, settingsClearLabel, settingsBackLabel:
+		return true
+	default:
+		return false
+	}
+}
+
+// replyKeyboard is a reply keyboard that stays open until another one replaces it.
+// is_persistent is newer than the Telegram library, hence the embedding.
+type replyKeyboard struct {
+	tgbotapi.ReplyKeyboardMarkup
+	IsPersistent bool `json:"is_persistent"`
+}
+
+// keyboardBuilder lays out keyboard buttons row by row
+type keyboardBuilder struct {
+	reply  [][]tgbotapi.KeyboardButton
+	inline [][]tgbotapi.InlineKeyboardButton
+}
+
+func newKeyboard() *keyboardBuilder {
+	return &keyboardBuilder{}
+}
+
+// row adds a row of reply buttons sending their labels
+func (k *keyboardBuilder) row(labels ...string) *keyboardBuilder {
+	var buttons []tgbotapi.KeyboardButton
+	for _, label := range labels {
+		buttons = append(buttons, tgbotapi.NewKeyboardButton(label))
+	}
+	k.reply = append(k.reply, buttons)
+	return k
+}
+
+// button adds a row with one inline button
+func (k *keyboardBuilder) button(text, data string) *keyboardBuilder {
+	k.inline = append(k.inline, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(text, data)))
+	return k
+}
+
+// buttons adds a row of inline buttons, given as text and data pairs
+func (k *keyboardBuilder) buttons(textAndData ...string) *keyboardBuilder {
+	var row []tgbotapi.InlineKeyboardButton
+	for i := 0; i+1 < len(textAndData); i += 2 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(textAndData[i], textAndData[i+1]))
+	}
+	k.inline = append(k.inline, row)
+	return k
+}
+
+func (k *keyboardBuilder) replyMarkup(placeholder string) replyKeyboard {
+	markup := tgbotapi.NewReplyKeyboard(k.reply...)
+	markup.ResizeKeyboard = true
+	markup.InputFieldPlaceholder = placeholder
+	return replyKeyboard{ReplyKeyboardMarkup: markup, IsPersistent: true}
+}
+
+// inlineMarkup returns the inline keyboard. Without buttons it removes the
+// keyboard of an edited message, which needs an empty list rather than null.
+func (k *keyboardBuilder) inlineMarkup() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.InlineKeyboardMarkup{InlineKeyboard: append([][]tgbotapi.InlineKeyboardButton{}, k.inline...)}
+}
+
+// mainMenuKeyboard is the keyboard shown below the chat at all times
+func mainMenuKeyboard() replyKeyboard {
+	return newKeyboard().
+		row(menuSearchLabel, menuFavoritesLabel).
+		row(menuAlertsLabel, menuSettingsLabel).
+		replyMarkup(keyboardPlaceholderText)
+}
+
+// settingsKeyboard replaces the main menu while the user changes settings
+func settingsKeyboard(alertsMuted bool) replyKeyboard {
+	alerts := settingsAlertsOnLabel
+	if alertsMuted {
+		alerts = settingsAlertsOffLabel
+	}
+	return newKeyboard().
+		row(alerts).
+		row(settingsClearLabel).
+		row(settingsBackLabel).
+		replyMarkup(keyboardPlaceholderText)
+}
+
+// routeMenuKeyboard offers the home, work and other routes, preceded by the
+// route of the chat when one was set with /setroute
+func (s *Service) routeMenuKeyboard(chatID int64) tgbotapi.InlineKeyboardMarkup {
+	keyboard := newKeyboard()
+	if r, ok := s.profile(chatID).defaultRoute(); ok {
+		keyboard.button(fmt.Sprintf("%s %s", trainEmoji, s.routeName(r)), s.callbacks.encode(actionRoute, encodeRoute(r)))
+	}
+	return keyboard.
+		buttons(
+			fmt.Sprintf("בית %s", homeEmoji), s.callbacks.encode(actionHome, ""),
+			fmt.Sprintf("עבודה %s", workEmoji), s.callbacks.encode(actionWork, ""),
+			fmt.Sprintf("אחר %s", searchEmoji), s.callbacks.encode(actionOther, "")).
+		inlineMarkup()
+}
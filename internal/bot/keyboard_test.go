@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestReplyKeyboards(t *testing.T) {
+	tests := []struct {
+		name     string
+		keyboard replyKeyboard
+		labels   []string
+		missing  string
+	}{
+		{
+			name:     "Main menu",
+			keyboard: mainMenuKeyboard(),
+			labels:   []string{menuSearchLabel, menuFavoritesLabel, menuAlertsLabel, menuSettingsLabel},
+		},
+		{
+			name:     "Settings with alerts on",
+			keyboard: settingsKeyboard(false),
+			labels:   []string{settingsAlertsOnLabel, settingsClearLabel, settingsBackLabel},
+			missing:  settingsAlertsOffLabel,
+		},
+		{
+			name:     "Settings with alerts muted",
+			keyboard: settingsKeyboard(true),
+			labels:   []string{settingsAlertsOffLabel, settingsClearLabel, settingsBackLabel},
+			missing:  settingsAlertsOnLabel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.keyboard)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			text := string(data)
+
+			for _, want := range []string{`"is_persistent":true`, `"resize_keyboard":true`} {
+				if !strings.Contains(text, want) {
+					t.Errorf("keyboard %s misses %s", text, want)
+				}
+			}
+			for _, label := range tt.labels {
+				if !strings.Contains(text, label) {
+					t.Errorf("keyboard misses %q", label)
+				}
+			}
+			if tt.missing != "" && strings.Contains(text, tt.missing) {
+				t.Errorf("keyboard shows %q", tt.missing)
+			}
+		})
+	}
+}
+
+func TestEmptyInlineKeyboard(t *testing.T) {
+	data, err := json.Marshal(newKeyboard().inlineMarkup())
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if got, want := string(data), `{"inline_keyboard":[]}`; got != want {
+		t.Errorf("empty inline keyboard = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"rail-go/internal/logger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// sendMainMenu sends text together with the persistent main menu keyboard
+func (s *Service) sendMainMenu(ctx context.Context, chatID int64, text string) error {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = mainMenuKeyboard()
+	_, err := s.send(ctx, chatID, msg)
+	return err
+}
+
+// handleMenuButton reacts to the labels of the reply keyboards. It reports false
+// when the text is not a menu button.
+func (s *Service) handleMenuButton(ctx context.Context, msg *tgbotapi.Message) (bool, error) {
+	chatID := msg.Chat.ID
+	switch msg.Text {
+	case menuSearchLabel:
+		return true, s.sendRouteMenu(ctx, chatID, fmt.Sprintf("%s בחר יעד:", trainEmoji))
+	case menuFavoritesLabel:
+		return true, s.sendFavorites(ctx, chatID)
+	case menuAlertsLabel:
+		return true, s.handleAlerts(ctx, msg)
+	case menuSettingsLabel:
+		return true, s.sendSettings(ctx, chatID, "⚙️ הגדרות:")
+	case settingsAlertsOnLabel, settingsAlertsOffLabel:
+		p := s.profile(chatID)
+		p.mu.Lock()
+		p.AlertsMuted = !p.AlertsMuted
+		muted := p.AlertsMuted
+		p.mu.Unlock()
+
+		logger.FromContext(ctx).Info("alert notifications toggled", "muted", muted)
+		text := fmt.Sprintf("%s עדכונים על שיבושים במסלולים שלך יישלחו אוטומטית.", successEmoji)
+		if muted {
+			text = fmt.Sprintf("%s עדכונים אוטומטיים כבויים, למעט מסלולים עם 🔔 התראה. אפשר לבדוק שיבושים דרך %s.", successEmoji, menuAlertsLabel)
+		}
+		return true, s.sendSettings(ctx, chatID, text)
+	case settingsClearLabel:
+		p := s.profile(chatID)
+		p.mu.Lock()
+		p.Routes = nil
+		p.mu.Unlock()
+
+		logger.FromContext(ctx).Info("saved routes cleared")
+		return true, s.sendSettings(ctx, chatID, fmt.Sprintf("%s המסלולים האחרונים נמחקו.", successEmoji))
+	case settingsBackLabel:
+		return true, s.sendMainMenu(ctx, chatID, fmt.Sprintf("%s תפריט ראשי", trainEmoji))
+	default:
+		return false, nil
+	}
+}
+
+// sendSettings shows the settings keyboard with the current settings of the chat
+func (s *Service) sendSettings(ctx context.Context, chatID int64, text string) error {
+	p := s.profile(chatID)
+	p.mu.Lock()
+	muted := p.AlertsMuted
+	p.mu.Unlock()
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = settingsKeyboard(muted)
+	_, err := s.send(ctx, chatID, msg)
+	return err
+}
@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"rail-go/internal/logger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// loadingText stands in for a schedule while it is fetched
+const loadingText = "⏳ מחפש רכבות..."
+
+// editMessage replaces the text of a message the bot sent. A nil markup removes
+// its keyboard, so a finished step leaves no buttons behind.
+func (s *Service) editMessage(ctx context.Context, chatID int64, messageID int, text string, markup *tgbotapi.InlineKeyboardMarkup) error {
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ReplyMarkup = markup
+	if err := s.request(ctx, chatID, edit); err != nil {
+		return fmt.Errorf("failed to edit message: %w", err)
+	}
+	return nil
+}
+
+// sendOrEdit edits the message when messageID is set and sends a new one otherwise
+func (s *Service) sendOrEdit(ctx context.Context, chatID int64, messageID int, text string, markup *tgbotapi.InlineKeyboardMarkup) (int, error) {
+	if messageID != 0 {
+		return messageID, s.editMessage(ctx, chatID, messageID, text, markup)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	if markup != nil {
+		msg.ReplyMarkup = *markup
+	}
+	sent, err := s.send(ctx, chatID, msg)
+	if err != nil {
+		return 0, err
+	}
+	return sent.MessageID, nil
+}
+
+// showSchedule shows the trains of a route in the message with messageID, or in a
+// new message when it is 0. The message reads loadingText while the schedule is
+// fetched. A zero at means now; otherwise the requested time heads the schedule.
+func (s *Service) showSchedule(ctx context.Context, conv conversation, messageID int, from, to string, at time.Time) error {
+	chatID := conv.chatID
+	messageID, err := s.sendOrEdit(ctx, chatID, messageID, loadingText, nil)
+	if err != nil {
+		return err
+	}
+
+	when := at
+	if when.IsZero() {
+		when = time.Now()
+	}
+	schedule, err := s.trainService.GetScheduleAt(ctx, from, to, when)
+	if err != nil {
+		if editErr := s.editMessage(ctx, chatID, messageID,
+			fmt.Sprintf("%s לא הצלחנו לקבל את לוח הזמנים. אנא נסה שוב.", warningEmoji), nil); editErr != nil {
+			logger.FromContext(ctx).Error("failed to replace loading message", "error", editErr)
+		}
+		return fmt.Errorf("failed to get schedule: %w", err)
+	}
+	s.rememberRoute(chatID, from, to)
+
+	logger.FromContext(ctx).Info("train schedule retrieved successfully",
+		"from", from,
+		"to", to)
+
+	// The chunks are shared with the schedule cache
+	messages := slices.Clone(schedule.Chunks)
+	if len(messages) == 0 {
+		messages = []string{fmt.Sprintf("%s לא נמצאו רכבות.", trainEmoji)}
+	}
+	if !at.IsZero() {
+		messages[0] = fmt.Sprintf("%s %s ← %s (%s)\n\n%s", trainEmoji,
+			s.trainService.GetStationName(from), s.trainService.GetStationName(to),
+			at.Format("02/01 15:04"), messages[0])
+	}
+
+	markup := s.resultKeyboard(chatID, route{From: from, To: to}, laterFrom(schedule))
+	_, err = s.sendMessages(ctx, chatID, messageID, messages, &markup)
+	return err
+}
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestShowSchedule(t *testing.T) {
+	at := time.Date(2025, 5, 4, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		messageID int
+		want      []telegramCall
+	}{
+		{
+			name:      "Edits the pressed message",
+			messageID: 5,
+			want: []telegramCall{
+				{method: "editMessageText", params: map[string][]string{"message_id": {"5"}, "text": {loadingText}}},
+				{method: "editMessageText", params: map[string][]string{"message_id": {"5"}, "text": {"08:05"}, "reply_markup": {"f|4600-8700"}}},
+			},
+		},
+		{
+			name: "Sends a loading message first",
+			want: []telegramCall{
+				{method: "sendMessage", params: map[string][]string{"text": {loadingText}}},
+				{method: "editMessageText", params: map[string][]string{"message_id": {"1"}, "text": {"08:05"}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, telegram := newTestService(t)
+			if err := s.showSchedule(context.Background(), conversation{chatID: 42}, tt.messageID, "4600", "8700", at); err != nil {
+				t.Fatalf("showSchedule() error = %v", err)
+			}
+			assertCalls(t, telegram.requests(), tt.want)
+
+			if routes := s.profile(42).savedRoutes(); len(routes) != 1 || routes[0] != (route{From: "4600", To: "8700"}) {
+				t.Errorf("saved routes = %v, want the searched route", routes)
+			}
+		})
+	}
+}
+
+func TestStationSelectionEditsMessage(t *testing.T) {
+	s, telegram := newTestService(t)
+	s.userState.Store("state_42", "awaitingFromStationSelection")
+	s.userState.Store("route_42", map[string]string{})
+
+	query := &tgbotapi.CallbackQuery{
+		ID:      "q1",
+		Data:    s.callbacks.encode(actionFromStation, "4600"),
+		Message: &tgbotapi.Message{MessageID: 9, Chat: &tgbotapi.Chat{ID: 42}},
+	}
+	if err := s.handleCallbackQuery(context.Background(), query); err != nil {
+		t.Fatalf("handleCallbackQuery() error = %v", err)
+	}
+
+	calls := telegram.requests()
+	assertCalls(t, calls, []telegramCall{
+		{method: "editMessageText", params: map[string][]string{"message_id": {"9"}, "text": {"תחנת היעד"}}},
+	})
+	if len(calls) == 1 && calls[0].params.Get("reply_markup") != "" {
+		t.Errorf("station keyboard kept after selection: %s", calls[0].params.Get("reply_markup"))
+	}
+	if state := load(s, "state_42"); state != "awaitingToStation" {
+		t.Errorf("state = %v, want awaitingToStation", state)
+	}
+
+	// The same button pressed again belongs to a finished step
+	if err := s.handleCallbackQuery(context.Background(), query); err != nil {
+		t.Fatalf("handleCallbackQuery() error = %v", err)
+	}
+	assertCalls(t, telegram.requests()[1:], []telegramCall{
+		{method: "editMessageReplyMarkup", params: map[string][]string{"message_id": {"9"}, "reply_markup": {`"inline_keyboard":[]`}}},
+		{method: "sendMessage", params: map[string][]string{"text": {"כבר לא בתוקף"}}},
+	})
+}
+
+// assertCalls checks the methods in order and that each listed parameter
+// contains the wanted text
+func assertCalls(t *testing.T, got, want []telegramCall) {
+	t.Helper()
+	if len(got) != len(want) {
+		var methods []string
+		for _, call := range got {
+			methods = append(methods, call.method)
+		}
+		t.Fatalf("got %d calls %v, want %d", len(got), methods, len(want))
+	}
+	for i := range want {
+		if got[i].method != want[i].method {
+			t.Errorf("call %d: method = %s, want %s", i, got[i].method, want[i].method)
+		}
+		for key, values := range want[i].params {
+			value := got[i].params.Get(key)
+			for _, want := range values {
+				if !strings.Contains(value, want) {
+					t.Errorf("call %d (%s): %s = %q, want it to contain %q", i, got[i].method, key, value, want)
+				}
+			}
+		}
+	}
+}
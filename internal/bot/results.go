@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"rail-go/internal/logger"
+	"rail-go/internal/train"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// laterStep is how much later the "later trains" shortcut searches when the
+// schedule had no trains to continue from
+const laterStep = time.Hour
+
+// resultKeyboard holds the shortcuts under a schedule. The buttons carry the
+// route, and "later trains" the time to search from, so the shortcuts of every
+// schedule shown keep working.
+func (s *Service) resultKeyboard(chatID int64, r route, later time.Time) tgbotapi.InlineKeyboardMarkup {
+	p := s.profile(chatID)
+
+	favorite := "⭐ הוספה למועדפים"
+	if p.isFavorite(r) {
+		favorite = "✖️ הסרה מהמועדפים"
+	}
+	alert := "🔔 התראה על שיבושים"
+	if p.hasAlert(r) {
+		alert = "🔕 ביטול התראה"
+	}
+
+	return newKeyboard().
+		buttons(
+			"🔁 כיוון הפוך", s.callbacks.encode(actionReverse, encodeRoute(r)),
+			"⏩ רכבות מאוחרות יותר", s.callbacks.encode(actionLater, encodeLater(r, later))).
+		buttons(
+			favorite, s.callbacks.encode(actionFavorite, encodeRoute(r)),
+			alert, s.callbacks.encode(actionAlert, encodeRoute(r))).
+		inlineMarkup()
+}
+
+// encodeLater writes a route and the time to search from as "<from>-<to>@<unix time>"
+func encodeLater(r route, at time.Time) string {
+	return encodeRoute(r) + "@" + strconv.FormatInt(at.Unix(), 10)
+}
+
+// decodeLater reads the payload written by encodeLater
+func decodeLater(data string) (route, time.Time, bool) {
+	routeData, unix, ok := strings.Cut(data, "@")
+	if !ok {
+		return route{}, time.Time{}, false
+	}
+	r, ok := decodeRoute(routeData)
+	if !ok {
+		return route{}, time.Time{}, false
+	}
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return route{}, time.Time{}, false
+	}
+	return r, time.Unix(seconds, 0), true
+}
+
+// laterFrom returns when the "later trains" shortcut of a schedule searches
+// from: just after its last train, or laterStep after its search when it had
+// none. The search already starts after a Shabbat or holiday, so the shortcut
+// does not show the same trains again.
+func laterFrom(schedule train.ScheduleMessage) time.Time {
+	if !schedule.LastDeparture.IsZero() {
+		return schedule.LastDeparture.Add(time.Minute)
+	}
+	return schedule.From.Add(laterStep)
+}
+
+// laterOf returns the time the "later trains" button of a result keyboard
+// searches from, so a keyboard rebuilt for a toggled star or alert keeps it
+func (s *Service) laterOf(markup *tgbotapi.InlineKeyboardMarkup) time.Time {
+	if markup != nil {
+		for _, row := range markup.InlineKeyboard {
+			for _, button := range row {
+				if button.CallbackData == nil {
+					continue
+				}
+				data, err := s.callbacks.decode(*button.CallbackData)
+				if err != nil || data.action != actionLater {
+					continue
+				}
+				if _, at, ok := decodeLater(data.payload); ok {
+					return at
+				}
+			}
+		}
+	}
+	return time.Now().Add(laterStep)
+}
+
+// handleResultShortcut runs the reverse, later and alert shortcuts of a schedule
+func (s *Service) handleResultShortcut(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackData) error {
+	chatID := query.Message.Chat.ID
+	conv := queryConversation(query)
+
+	var r route
+	var later time.Time
+	var ok bool
+	if data.action == actionLater {
+		r, later, ok = decodeLater(data.payload)
+	} else {
+		r, ok = decodeRoute(data.payload)
+	}
+	if !ok {
+		// Buttons from before the shortcuts carried their route
+		logger.FromContext(ctx).Info("shortcut without a route pressed",
+			"action", data.action)
+		return s.restartFlow(ctx, conv, query.Message)
+	}
+
+	logger.FromContext(ctx).Info("handling result shortcut",
+		"action", data.action,
+		"from", r.From,
+		"to", r.To)
+
+	switch data.action {
+	case actionReverse:
+		return s.showSchedule(ctx, conv, 0, r.To, r.From, time.Time{})
+	case actionLater:
+		return s.showSchedule(ctx, conv, 0, r.From, r.To, later)
+	default:
+		set, ok := s.profile(chatID).toggleAlert(r)
+		if !ok {
+			return s.SendMessage(ctx, chatID,
+				fmt.Sprintf("%s אפשר להגדיר התראות לעד %d מסלולים.", warningEmoji, maxAlertRoutes))
+		}
+		logger.FromContext(ctx).Info("route alert toggled", "set", set)
+
+		markup := s.resultKeyboard(chatID, r, s.laterOf(query.Message.ReplyMarkup))
+		edit := tgbotapi.NewEditMessageReplyMarkup(chatID, query.Message.MessageID, markup)
+		if err := s.request(ctx, chatID, edit); err != nil {
+			return fmt.Errorf("failed to update alert button: %w", err)
+		}
+		return nil
+	}
+}
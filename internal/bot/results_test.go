@@ -0,0 +1,125 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"rail-go/internal/train"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestResultShortcuts(t *testing.T) {
+	at := time.Date(2025, 5, 4, 8, 0, 0, 0, time.UTC)
+	shown := route{From: "4600", To: "8700"}
+	// The fake rail API answers with one train, departing at 08:05
+	later := time.Date(2025, 5, 4, 8, 6, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		action    callbackAction
+		payload   string
+		wantAlert bool
+		calls     []telegramCall
+	}{
+		{
+			name:    "Reverse",
+			action:  actionReverse,
+			payload: encodeRoute(shown),
+			calls: []telegramCall{
+				{method: "sendMessage", params: map[string][]string{"text": {loadingText}}},
+				{method: "editMessageText", params: map[string][]string{"reply_markup": {"f|8700-4600"}}},
+			},
+		},
+		{
+			name:    "Later",
+			action:  actionLater,
+			payload: encodeLater(shown, later),
+			calls: []telegramCall{
+				{method: "sendMessage", params: map[string][]string{"text": {loadingText}}},
+				{method: "editMessageText", params: map[string][]string{"text": {later.Local().Format("15:04")}, "reply_markup": {"f|4600-8700"}}},
+			},
+		},
+		{
+			name:      "Alert",
+			action:    actionAlert,
+			payload:   encodeRoute(shown),
+			wantAlert: true,
+			calls: []telegramCall{
+				{method: "editMessageReplyMarkup", params: map[string][]string{"message_id": {"3"}, "reply_markup": {"🔕", encodeLater(shown, later)}}},
+			},
+		},
+		{
+			name:   "Button without a route",
+			action: actionReverse,
+			calls: []telegramCall{
+				{method: "editMessageReplyMarkup", params: map[string][]string{"message_id": {"3"}}},
+				{method: "sendMessage", params: map[string][]string{"text": {"כבר לא בתוקף"}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, telegram := newTestService(t)
+			if err := s.showSchedule(context.Background(), conversation{chatID: 42}, 5, shown.From, shown.To, at); err != nil {
+				t.Fatalf("showSchedule() error = %v", err)
+			}
+			calls := telegram.requests()
+			if markup := calls[len(calls)-1].params.Get("reply_markup"); !strings.Contains(markup, encodeLater(shown, later)) {
+				t.Fatalf("later button of the schedule = %s, want it to search from %v", markup, later)
+			}
+
+			// Shortcuts of older results work too, so press them on message 3
+			keyboard := s.resultKeyboard(42, shown, later)
+			err := s.handleCallbackQuery(context.Background(), &tgbotapi.CallbackQuery{
+				ID:   "q1",
+				Data: s.callbacks.encode(tt.action, tt.payload),
+				Message: &tgbotapi.Message{
+					MessageID:   3,
+					Chat:        &tgbotapi.Chat{ID: 42},
+					ReplyMarkup: &keyboard,
+				},
+			})
+			if err != nil {
+				t.Fatalf("pressing %s error = %v", tt.action, err)
+			}
+			assertCalls(t, telegram.requests()[len(calls):], tt.calls)
+
+			if got := s.profile(42).hasAlert(shown); got != tt.wantAlert {
+				t.Errorf("alert set = %v, want %v", got, tt.wantAlert)
+			}
+		})
+	}
+}
+
+func TestLaterFrom(t *testing.T) {
+	resumes := time.Date(2025, 5, 3, 19, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule train.ScheduleMessage
+		want     time.Time
+	}{
+		{
+			name:     "After the last train shown",
+			schedule: train.ScheduleMessage{From: resumes, LastDeparture: resumes.Add(2 * time.Hour)},
+			want:     resumes.Add(2*time.Hour + time.Minute),
+		},
+		{
+			name:     "No trains after Shabbat",
+			schedule: train.ScheduleMessage{From: resumes},
+			want:     resumes.Add(laterStep),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := laterFrom(tt.schedule); !got.Equal(tt.want) {
+				t.Errorf("laterFrom() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"rail-go/internal/config"
+	"rail-go/internal/logger"
+	"rail-go/internal/metrics"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// sendPriority orders requests waiting for the same send budget
+type sendPriority int
+
+const (
+	// priorityBackground is for pushes nobody is waiting for: scheduled
+	// notifications, disruption alerts and broadcasts
+	priorityBackground sendPriority = iota
+	// priorityInteractive is for replies to an update
+	priorityInteractive
+)
+
+func (p sendPriority) String() string {
+	if p == priorityInteractive {
+		return "interactive"
+	}
+	return "background"
+}
+
+type priorityKey struct{}
+
+// withPriority marks the Telegram requests made with ctx
+func withPriority(ctx context.Context, priority sendPriority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// priorityFromContext returns the priority set with withPriority. Requests
+// made outside of an update, e.g. by scheduled tasks, are background.
+func priorityFromContext(ctx context.Context) sendPriority {
+	if priority, ok := ctx.Value(priorityKey{}).(sendPriority); ok {
+		return priority
+	}
+	return priorityBackground
+}
+
+const (
+	// chatSendBurst is how many messages a chat may get at once, e.g. the parts
+	// of a long schedule
+	chatSendBurst = 3
+	// interactiveReserve is the part of the global budget pushes leave to replies
+	interactiveReserve = 0.25
+	// maxSendAttempts bounds the retries of a request Telegram asked to slow down
+	maxSendAttempts = 3
+	// idleChatTTL is how long the bucket of a quiet chat is kept
+	idleChatTTL = 10 * time.Minute
+)
+
+// errSendQueueClosed is returned for pushes still waiting when the bot shuts down
+var errSendQueueClosed = errors.New("send queue closed")
+
+// sendBucket is a token bucket that also tells how long until its next token
+type sendBucket struct {
+	tokens float64
+	perSec float64
+	burst  float64
+	last   time.Time
+	// pausedUntil is set from the retry_after of a 429 response
+	pausedUntil time.Time
+}
+
+func newSendBucket(perSec, burst float64, now time.Time) *sendBucket {
+	return &sendBucket{tokens: burst, perSec: perSec, burst: burst, last: now}
+}
+
+func (b *sendBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed.Seconds()*b.perSec)
+	}
+	b.last = now
+}
+
+// wait returns how long until the bucket holds more than keep tokens
+func (b *sendBucket) wait(now time.Time, keep float64) time.Duration {
+	if now.Before(b.pausedUntil) {
+		return b.pausedUntil.Sub(now)
+	}
+	b.refill(now)
+	if missing := keep + 1 - b.tokens; missing > 0 {
+		return time.Duration(missing / b.perSec * float64(time.Second))
+	}
+	return 0
+}
+
+// sendLimits are the budgets of the send queue; zero disables a limit
+type sendLimits struct {
+	perSecond          int
+	chatPerMinute      int
+	groupChatPerMinute int
+}
+
+func sendLimitsFromConfig(cfg *config.Config) sendLimits {
+	return sendLimits{
+		perSecond:          cfg.RateLimit.SendPerSecond,
+		chatPerMinute:      cfg.RateLimit.ChatSendPerMinute,
+		groupChatPerMinute: cfg.RateLimit.GroupSendPerMinute,
+	}
+}
+
+// sendQueue paces outgoing Telegram requests so the bot stays within the Bot API
+// limits: about 30 messages per second overall, one per second in a chat and 20
+// per minute in a group. Requests wait in their own goroutine until the budgets
+// allow them; pushes leave part of the global budget to interactive replies, so a
+// broadcast does not delay the answer to a user.
+type sendQueue struct {
+	mu     sync.Mutex
+	limits sendLimits
+	global *sendBucket
+	chats  map[int64]*sendBucket
+	// changed is closed and replaced to wake the waiting requests when the
+	// limits change or the queue closes
+	changed     chan struct{}
+	closed      bool
+	lastCleanup time.Time
+	now         func() time.Time
+}
+
+func newSendQueue(limits sendLimits) *sendQueue {
+	q := &sendQueue{chats: make(map[int64]*sendBucket), changed: make(chan struct{}), now: time.Now}
+	q.resetBuckets(limits)
+	return q
+}
+
+// setLimits changes the limits. A config reload with the same limits keeps the
+// buckets as they are, so it does not hand every chat a fresh burst.
+func (q *sendQueue) setLimits(limits sendLimits) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if limits == q.limits {
+		return
+	}
+	q.resetBuckets(limits)
+	q.notify()
+}
+
+// resetBuckets rebuilds the buckets for new limits. Chats Telegram asked to slow
+// down stay paused.
+func (q *sendQueue) resetBuckets(limits sendLimits) {
+	now := q.now()
+	q.limits = limits
+	q.global = nil
+	if limits.perSecond > 0 {
+		q.global = newSendBucket(float64(limits.perSecond), float64(limits.perSecond), now)
+	}
+
+	paused := q.chats
+	q.chats = make(map[int64]*sendBucket)
+	for chatID, bucket := range paused {
+		if now.Before(bucket.pausedUntil) {
+			q.pauseUntil(chatID, bucket.pausedUntil, now)
+		}
+	}
+	q.lastCleanup = now
+}
+
+// acquire waits until a request to chatID may be sent. Edits of existing
+// messages only count against the global budget, but wait for a paused chat.
+func (q *sendQueue) acquire(ctx context.Context, chatID int64, edit bool, priority sendPriority) error {
+	start := q.now()
+	defer func() {
+		metrics.SendQueueWait.WithLabelValues(priority.String()).Observe(q.now().Sub(start).Seconds())
+	}()
+
+	q.mu.Lock()
+	for {
+		if q.closed && priority == priorityBackground {
+			q.mu.Unlock()
+			return errSendQueueClosed
+		}
+
+		now := q.now()
+		q.cleanup(now)
+		wait := q.wait(chatID, edit, priority, now)
+		if wait <= 0 {
+			q.take(chatID, edit)
+			q.mu.Unlock()
+			return nil
+		}
+		changed := q.changed
+		q.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-changed:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		q.mu.Lock()
+	}
+}
+
+// wait returns how long the request has to wait for both budgets
+func (q *sendQueue) wait(chatID int64, edit bool, priority sendPriority, now time.Time) time.Duration {
+	var wait time.Duration
+	if q.global != nil {
+		keep := 0.0
+		if priority == priorityBackground {
+			keep = math.Floor(q.global.burst * interactiveReserve)
+		}
+		wait = q.global.wait(now, keep)
+	}
+	if bucket := q.chat(chatID, now); bucket != nil {
+		if edit {
+			wait = max(wait, bucket.pausedUntil.Sub(now))
+		} else {
+			wait = max(wait, bucket.wait(now, 0))
+		}
+	}
+	return wait
+}
+
+func (q *sendQueue) take(chatID int64, edit bool) {
+	if q.global != nil {
+		q.global.tokens--
+	}
+	if bucket := q.chats[chatID]; bucket != nil && !edit {
+		bucket.tokens--
+	}
+}
+
+// chat returns the bucket of a chat, or nil when chats are not limited. Group
+// chats have negative IDs and a lower limit.
+func (q *sendQueue) chat(chatID int64, now time.Time) *sendBucket {
+	if bucket, ok := q.chats[chatID]; ok {
+		return bucket
+	}
+	perMinute := q.limits.chatPerMinute
+	if chatID < 0 {
+		perMinute = q.limits.groupChatPerMinute
+	}
+	if perMinute <= 0 {
+		return nil
+	}
+	bucket := newSendBucket(float64(perMinute)/60, chatSendBurst, now)
+	q.chats[chatID] = bucket
+	return bucket
+}
+
+// pause holds back the requests to chatID for the retry_after Telegram
+// answered with
+func (q *sendQueue) pause(chatID int64, d time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.pauseUntil(chatID, now.Add(d), now)
+}
+
+func (q *sendQueue) pauseUntil(chatID int64, until, now time.Time) {
+	bucket := q.chat(chatID, now)
+	if bucket == nil {
+		// The chat is not limited, so it gets a bucket just for the pause
+		bucket = newSendBucket(math.Inf(1), 1, now)
+		q.chats[chatID] = bucket
+	}
+	bucket.pausedUntil = until
+}
+
+// close makes waiting and future pushes fail with errSendQueueClosed, while
+// interactive replies are still sent
+func (q *sendQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.notify()
+}
+
+func (q *sendQueue) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// cleanup drops the buckets of chats that have been quiet for a while
+func (q *sendQueue) cleanup(now time.Time) {
+	if now.Sub(q.lastCleanup) < idleChatTTL {
+		return
+	}
+	for chatID, bucket := range q.chats {
+		if now.Sub(bucket.last) > idleChatTTL && now.After(bucket.pausedUntil) {
+			delete(q.chats, chatID)
+		}
+	}
+	q.lastCleanup = now
+}
+
+// send sends a new message to chatID through the send queue
+func (s *Service) send(ctx context.Context, chatID int64, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	var sent tgbotapi.Message
+	err := s.paced(ctx, chatID, false, func() error {
+		var err error
+		sent, err = s.bot.Send(c)
+		return err
+	})
+	return sent, err
+}
+
+// request changes an existing message of chatID, e.g. edits its text or
+// keyboard, through the send queue
+func (s *Service) request(ctx context.Context, chatID int64, c tgbotapi.Chattable) error {
+	return s.paced(ctx, chatID, true, func() error {
+		_, err := s.bot.Request(c)
+		return err
+	})
+}
+
+// paced runs a Telegram request once the send queue allows it, and again after
+// the retry_after of a 429 response
+func (s *Service) paced(ctx context.Context, chatID int64, edit bool, do func() error) error {
+	priority := priorityFromContext(ctx)
+	for attempt := 1; ; attempt++ {
+		if err := s.sendQueue.acquire(ctx, chatID, edit, priority); err != nil {
+			return fmt.Errorf("failed to send: %w", err)
+		}
+
+		err := do()
+		var apiErr *tgbotapi.Error
+		if !errors.As(err, &apiErr) || apiErr.RetryAfter <= 0 {
+			return err
+		}
+		metrics.TelegramThrottled.Inc()
+		if attempt == maxSendAttempts {
+			return err
+		}
+
+		retryAfter := time.Duration(apiErr.RetryAfter) * time.Second
+		logger.FromContext(ctx).Warn("telegram asked to slow down",
+			"target_chat_id", chatID,
+			"retry_after", retryAfter.String(),
+			"attempt", attempt)
+		s.sendQueue.pause(chatID, retryAfter)
+	}
+}
+
+// CloseSendQueue stops the pushes waiting to be sent, so that a shutdown does not
+// wait for a broadcast or an alert round. Replies to updates are still sent.
+func (s *Service) CloseSendQueue(context.Context) error {
+	s.sendQueue.close()
+	return nil
+}
@@ -0,0 +1,184 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSendQueueWait(t *testing.T) {
+	start := time.Date(2025, 5, 4, 8, 0, 0, 0, time.UTC)
+	limits := sendLimits{perSecond: 4, chatPerMinute: 60, groupChatPerMinute: 20}
+
+	type send struct {
+		chatID   int64
+		edit     bool
+		priority sendPriority
+	}
+	tests := []struct {
+		name string
+		sent []send
+		next send
+		want time.Duration
+	}{
+		{
+			name: "Within the chat burst",
+			sent: []send{{chatID: 7}, {chatID: 7}},
+			next: send{chatID: 7},
+			want: 0,
+		},
+		{
+			name: "Private chat after its burst",
+			sent: []send{{chatID: 7}, {chatID: 7}, {chatID: 7}},
+			next: send{chatID: 7},
+			want: time.Second,
+		},
+		{
+			name: "Group after its burst",
+			sent: []send{{chatID: -100}, {chatID: -100}, {chatID: -100}},
+			next: send{chatID: -100},
+			want: 3 * time.Second,
+		},
+		{
+			name: "Edits do not count against the chat",
+			sent: []send{{chatID: 7}, {chatID: 7}, {chatID: 7}},
+			next: send{chatID: 7, edit: true, priority: priorityInteractive},
+			want: 0,
+		},
+		{
+			name: "Global limit",
+			sent: []send{{chatID: 1}, {chatID: 2}, {chatID: 3}, {chatID: 4, priority: priorityInteractive}},
+			next: send{chatID: 5, priority: priorityInteractive},
+			want: 250 * time.Millisecond,
+		},
+		{
+			name: "Pushes leave the reserve to replies",
+			sent: []send{{chatID: 1}, {chatID: 2}, {chatID: 3}},
+			next: send{chatID: 4},
+			want: 250 * time.Millisecond,
+		},
+		{
+			name: "Replies use the reserve",
+			sent: []send{{chatID: 1}, {chatID: 2}, {chatID: 3}},
+			next: send{chatID: 4, priority: priorityInteractive},
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newSendQueue(sendLimits{})
+			q.now = func() time.Time { return start }
+			q.setLimits(limits)
+
+			for i, sent := range tt.sent {
+				if wait := q.wait(sent.chatID, sent.edit, sent.priority, start); wait > 0 {
+					t.Fatalf("send %d waits %v", i, wait)
+				}
+				q.take(sent.chatID, sent.edit)
+			}
+			if got := q.wait(tt.next.chatID, tt.next.edit, tt.next.priority, start); got != tt.want {
+				t.Errorf("wait() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendQueuePause(t *testing.T) {
+	start := time.Date(2025, 5, 4, 8, 0, 0, 0, time.UTC)
+	q := newSendQueue(sendLimits{})
+	q.now = func() time.Time { return start }
+
+	q.pause(7, 2*time.Second)
+	if got := q.wait(7, false, priorityInteractive, start); got != 2*time.Second {
+		t.Errorf("wait() of paused chat = %v, want 2s", got)
+	}
+	if got := q.wait(7, true, priorityInteractive, start); got != 2*time.Second {
+		t.Errorf("wait() of edit in paused chat = %v, want 2s", got)
+	}
+	if got := q.wait(8, false, priorityInteractive, start); got != 0 {
+		t.Errorf("wait() of other chat = %v, want 0", got)
+	}
+	if got := q.wait(7, false, priorityInteractive, start.Add(2*time.Second)); got != 0 {
+		t.Errorf("wait() after the pause = %v, want 0", got)
+	}
+}
+
+func TestSendQueueSetLimits(t *testing.T) {
+	start := time.Date(2025, 5, 4, 8, 0, 0, 0, time.UTC)
+	limits := sendLimits{perSecond: 30, chatPerMinute: 60, groupChatPerMinute: 20}
+	q := newSendQueue(sendLimits{})
+	q.now = func() time.Time { return start }
+	q.setLimits(limits)
+
+	for range chatSendBurst {
+		q.wait(7, false, priorityInteractive, start)
+		q.take(7, false)
+	}
+	q.pause(8, 5*time.Second)
+
+	q.setLimits(limits)
+	if got := q.wait(7, false, priorityInteractive, start); got != time.Second {
+		t.Errorf("wait() after a reload with the same limits = %v, want 1s", got)
+	}
+
+	limits.chatPerMinute = 120
+	q.setLimits(limits)
+	if got := q.wait(7, false, priorityInteractive, start); got != 0 {
+		t.Errorf("wait() after the limits changed = %v, want 0", got)
+	}
+	if got := q.wait(8, false, priorityInteractive, start); got != 5*time.Second {
+		t.Errorf("wait() of paused chat after the limits changed = %v, want 5s", got)
+	}
+}
+
+func TestSendQueueClose(t *testing.T) {
+	q := newSendQueue(sendLimits{perSecond: 1, chatPerMinute: 60})
+	ctx := context.Background()
+
+	// Use up the budget so the push waits
+	if err := q.acquire(ctx, 7, false, priorityInteractive); err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- q.acquire(ctx, 8, false, priorityBackground)
+	}()
+
+	q.close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, errSendQueueClosed) {
+			t.Errorf("waiting push: acquire() error = %v, want errSendQueueClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiting push not released by close()")
+	}
+
+	if err := q.acquire(ctx, 9, false, priorityBackground); !errors.Is(err, errSendQueueClosed) {
+		t.Errorf("new push: acquire() error = %v, want errSendQueueClosed", err)
+	}
+	q.setLimits(sendLimits{})
+	if err := q.acquire(ctx, 9, false, priorityInteractive); err != nil {
+		t.Errorf("reply: acquire() error = %v", err)
+	}
+}
+
+func TestSendRetriesAfterRetryAfter(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for retry_after")
+	}
+
+	s, telegram := newTestService(t)
+	telegram.throttled = 1
+
+	ctx := withPriority(context.Background(), priorityInteractive)
+	if err := s.SendMessage(ctx, 42, "שלום"); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	assertCalls(t, telegram.requests(), []telegramCall{
+		{method: "sendMessage", params: map[string][]string{"text": {"שלום"}}},
+		{method: "sendMessage", params: map[string][]string{"text": {"שלום"}}},
+	})
+}
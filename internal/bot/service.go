@@ -3,13 +3,22 @@ package bot
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"rail-go/internal/config"
 	"rail-go/internal/logger"
+	"rail-go/internal/metrics"
+	"rail-go/internal/ratelimit"
 	"rail-go/internal/train"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Emoji constants
@@ -25,12 +34,42 @@ const (
 	cheeseEmoji  = "🧀"
 )
 
+var tracer = otel.Tracer("rail-go/internal/bot")
+
 type Service struct {
+	mu           sync.RWMutex
 	bot          *tgbotapi.BotAPI
 	config       *config.Config
 	logger       *logger.Logger
 	userState    *sync.Map
+	profiles     *sync.Map
 	trainService *train.Service
+	tasks        TaskController
+	stats        stats
+	limiter      *ratelimit.Limiter
+	// inlineLimiter is the looser budget of inline queries
+	inlineLimiter *ratelimit.Limiter
+	sendQueue     *sendQueue
+	updates       *dispatcher
+	// slowDownNotices holds when each rate-limited user was last told to slow down
+	slowDownNotices *sync.Map
+	// slowDownPruned is when old slow down notices were last dropped
+	slowDownPruned atomic.Int64
+	healthMu       sync.Mutex
+	telegram       telegramHealth
+	// apiEndpoint is the Bot API endpoint of bot, for the requests made without it
+	apiEndpoint string
+	reloader    func() error
+	commands    *commandRouter
+	callbacks   callbackCodec
+	// mention matches a mention of the bot in a group message, nil when the bot
+	// has no username
+	mention *regexp.Regexp
+	// loopDone is closed when Start stops handling updates
+	loopDone chan struct{}
+	// background counts the work started by an update that outlives it, like
+	// a broadcast
+	background sync.WaitGroup
 }
 
 func NewService(cfg *config.Config, log *logger.Logger) (*Service, error) {
@@ -41,41 +80,121 @@ func NewService(cfg *config.Config, log *logger.Logger) (*Service, error) {
 
 	bot.Debug = cfg.Bot.Debug
 
+	return newService(cfg, bot, log), nil
+}
+
+// newService wires the bot around a connected Telegram client
+func newService(cfg *config.Config, bot *tgbotapi.BotAPI, log *logger.Logger) *Service {
 	trainService := train.NewService(cfg, log)
 
-	return &Service{
+	s := &Service{
 		bot:          bot,
 		config:       cfg,
 		logger:       log,
 		userState:    &sync.Map{},
+		profiles:     &sync.Map{},
 		trainService: trainService,
-	}, nil
+		stats:        stats{startedAt: time.Now()},
+		limiter: ratelimit.New(cfg.RateLimit.UserPerMinute, cfg.RateLimit.UserBurst,
+			cfg.RateLimit.GlobalPerMinute, cfg.RateLimit.GlobalBurst),
+		inlineLimiter:   ratelimit.New(cfg.RateLimit.InlinePerMinute, cfg.RateLimit.InlineBurst, 0, 0),
+		sendQueue:       newSendQueue(sendLimitsFromConfig(cfg)),
+		updates:         newDispatcher(),
+		slowDownNotices: &sync.Map{},
+		callbacks:       newCallbackCodec(cfg.Bot.CallbackSecret),
+		apiEndpoint:     tgbotapi.APIEndpoint,
+	}
+	s.commands = newCommandRouter(s.botCommands())
+	s.mention = mentionPattern(bot.Self.UserName)
+	return s
+}
+
+// TrainService returns the train service used by the bot
+func (s *Service) TrainService() *train.Service {
+	return s.trainService
 }
 
+// Start receives and handles updates until ctx is cancelled. It then stops
+// intake; the updates being handled at that moment still run to completion.
+// Updates of the same chat are handled in order, those of different chats
+// concurrently.
 func (s *Service) Start(ctx context.Context) error {
 	s.logger.Info("bot service starting", "debug_mode", s.bot.Debug)
 
+	done := make(chan struct{})
+	s.mu.Lock()
+	s.loopDone = done
+	s.mu.Unlock()
+	defer close(done)
+
 	if err := s.sendStartupMessage(ctx); err != nil {
 		s.logger.Error("failed to send startup message", "error", err)
 	}
+	if err := s.syncCommands(); err != nil {
+		s.logger.Error("failed to register bot commands", "error", err)
+	}
 
 	updates := s.bot.GetUpdatesChan(tgbotapi.UpdateConfig{
 		Timeout: s.config.Bot.Timeout,
 	})
 
+	// Handlers outlive ctx so a shutdown does not abort a half-handled update
+	handlerCtx := context.WithoutCancel(ctx)
+
 	for {
 		select {
 		case <-ctx.Done():
-			s.logger.Info("bot service shutting down")
+			s.bot.StopReceivingUpdates()
+			s.logger.Info("bot service stopped receiving updates")
 			return ctx.Err()
-		case update := <-updates:
-			if err := s.handleUpdate(ctx, update); err != nil {
-				s.logger.Error("failed to handle update", "error", err)
+		case update, ok := <-updates:
+			if !ok {
+				return nil
 			}
+			s.updates.dispatch(updateChatID(update), func() {
+				start := time.Now()
+				err := s.handleUpdate(handlerCtx, update)
+				kind := updateType(update)
+				metrics.HandlerDuration.WithLabelValues(kind).Observe(time.Since(start).Seconds())
+				metrics.UpdatesHandled.WithLabelValues(kind, metrics.Outcome(err)).Inc()
+				if err != nil {
+					s.stats.errors.Add(1)
+				}
+			})
 		}
 	}
 }
 
+// Drain waits until Start has returned, the in-flight updates and their
+// replies are done, and the background work started by updates has finished,
+// or until ctx expires.
+func (s *Service) Drain(ctx context.Context) error {
+	s.mu.RLock()
+	done := s.loopDone
+	s.mu.RUnlock()
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return fmt.Errorf("in-flight updates not finished: %w", ctx.Err())
+		}
+	}
+
+	// Updates may start background work, so wait for them first
+	finished := make(chan struct{})
+	go func() {
+		s.updates.wait()
+		s.background.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("in-flight updates not finished: %w", ctx.Err())
+	}
+}
+
 // sendStartupMessage sends a notification message when the service starts
 func (s *Service) sendStartupMessage(ctx context.Context) error {
 	if s.config.Bot.AdminChatID == 0 {
@@ -91,33 +210,114 @@ func (s *Service) sendStartupMessage(ctx context.Context) error {
 }
 
 func (s *Service) handleUpdate(ctx context.Context, update tgbotapi.Update) error {
+	kind := updateType(update)
+	ctx, span := tracer.Start(ctx, "handleUpdate", trace.WithAttributes(
+		attribute.Int("telegram.update_id", update.UpdateID),
+		attribute.String("telegram.update_type", kind)))
+	defer span.End()
+
+	log := s.logger.With(
+		"update_id", update.UpdateID,
+		"correlation_id", logger.NewCorrelationID())
+	if span.SpanContext().IsValid() {
+		log = log.With("trace_id", span.SpanContext().TraceID().String())
+	}
+	if user := update.SentFrom(); user != nil {
+		log = log.With("user_id", user.ID)
+	}
+	if chat := update.FromChat(); chat != nil {
+		log = log.With("chat_id", chat.ID)
+	}
+	ctx = logger.NewContext(ctx, log)
+	// Replies to the update go before scheduled pushes
+	ctx = withPriority(ctx, priorityInteractive)
+
+	// A bug in one handler must not take down the bot with every other chat
+	var err error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("panic in update handler", "error", r)
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		err = s.routeUpdate(ctx, update)
+	}()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		log.Error("failed to handle update", "error", err)
+	}
+	return err
+}
+
+func (s *Service) routeUpdate(ctx context.Context, update tgbotapi.Update) error {
+	// Group chatter is dropped before it counts against the rate limit of its sender
+	if update.Message != nil && !s.addressedToBot(ctx, update.Message) {
+		return nil
+	}
+	if !s.admitUpdate(ctx, update) {
+		return nil
+	}
 	if update.CallbackQuery != nil {
-		s.logger.Info("received callback query",
-			"user_id", update.CallbackQuery.From.ID,
+		s.stats.callbacks.Add(1)
+		logger.FromContext(ctx).Info("received callback query")
+		logger.FromContext(ctx).Debug("callback query data",
 			"callback_data", update.CallbackQuery.Data)
 		return s.handleCallbackQuery(ctx, update.CallbackQuery)
 	}
+	if update.InlineQuery != nil {
+		s.stats.inlineQueries.Add(1)
+		logger.FromContext(ctx).Info("received inline query")
+		logger.FromContext(ctx).Debug("inline query text",
+			"query", update.InlineQuery.Query)
+		return s.handleInlineQuery(ctx, update.InlineQuery)
+	}
 	if update.Message != nil {
-		s.logger.Info("received message",
-			"user_id", update.Message.From.ID,
+		s.stats.messages.Add(1)
+		s.profile(update.Message.Chat.ID)
+		logger.FromContext(ctx).Info("received message")
+		logger.FromContext(ctx).Debug("message text",
 			"text", update.Message.Text)
 		return s.handleMessage(ctx, update.Message)
 	}
 	return nil
 }
 
+// updateType names the kind of update for metrics
+func updateType(update tgbotapi.Update) string {
+	switch {
+	case update.Message != nil:
+		return "message"
+	case update.CallbackQuery != nil:
+		return "callback_query"
+	case update.InlineQuery != nil:
+		return "inline_query"
+	default:
+		return "other"
+	}
+}
+
 func (s *Service) handleMessage(ctx context.Context, msg *tgbotapi.Message) error {
-	state, _ := s.userState.Load(fmt.Sprintf("state_%d", msg.Chat.ID))
+	conv := messageConversation(msg)
+	state, _ := s.userState.Load(conv.stateKey())
 	currentState, ok := state.(string)
 	if !ok {
 		currentState = "default"
 	}
 
-	s.logger.Info("handling message",
-		"user_id", msg.From.ID,
-		"chat_id", msg.Chat.ID,
-		"state", currentState,
-		"text", msg.Text)
+	logger.FromContext(ctx).Info("handling message",
+		"state", currentState)
+
+	// A command cancels whatever the user was in the middle of
+	if msg.IsCommand() {
+		s.userState.Delete(conv.stateKey())
+		return s.handleCommand(ctx, msg)
+	}
+	// A mention of the bot without a request asks for the route of the group
+	if conv.inGroup() && msg.Text == "" {
+		return s.handleGroupMention(ctx, msg)
+	}
 
 	switch currentState {
 	case "awaitingFromStation", "awaitingToStation":
@@ -128,199 +328,164 @@ func (s *Service) handleMessage(ctx context.Context, msg *tgbotapi.Message) erro
 }
 
 func (s *Service) handleCallbackQuery(ctx context.Context, query *tgbotapi.CallbackQuery) error {
-	callback := tgbotapi.NewCallback(query.ID, "")
-	if _, err := s.bot.Request(callback); err != nil {
-		s.logger.Error("failed to acknowledge callback", "error", err)
-	}
-
-	s.logger.Info("handling callback query",
-		"user_id", query.From.ID,
-		"chat_id", query.Message.Chat.ID,
-		"data", query.Data)
-
-	switch query.Data {
-	case "home":
-		return s.handleHomeRoute(ctx, query)
-	case "work":
-		return s.handleWorkRoute(ctx, query)
-	case "other":
-		return s.handleOtherRoute(ctx, query)
-	case "search_train":
-		return s.handleSearchTrain(ctx, query)
-	default:
-		return s.handleStationSelection(ctx, query)
+	if query.Message == nil {
+		// Buttons of messages sent in inline mode only come with an inline message ID
+		logger.FromContext(ctx).Info("callback without a message ignored")
+		callback := tgbotapi.NewCallback(query.ID, "")
+		if _, err := s.bot.Request(callback); err != nil {
+			logger.FromContext(ctx).Error("failed to acknowledge callback", "error", err)
+		}
+		return nil
 	}
-}
-
-func (s *Service) handleHomeRoute(ctx context.Context, query *tgbotapi.CallbackQuery) error {
-	s.logger.Info("handling home route request",
-		"user_id", query.From.ID,
-		"chat_id", query.Message.Chat.ID)
 
-	schedule, err := s.trainService.GetSchedule(ctx, "4600", "8700")
+	data, err := s.callbacks.decode(query.Data)
 	if err != nil {
-		s.logger.Error("failed to get home route schedule", "error", err)
-		return fmt.Errorf("failed to get home route schedule: %w", err)
+		return s.handleStaleCallback(ctx, query, err)
 	}
 
-	s.logger.Info("home route schedule retrieved successfully",
-		"user_id", query.From.ID,
-		"chat_id", query.Message.Chat.ID)
-
-	return s.SendMessages(ctx, query.Message.Chat.ID, schedule)
-}
-
-func (s *Service) handleWorkRoute(ctx context.Context, query *tgbotapi.CallbackQuery) error {
-	s.logger.Info("handling work route request",
-		"user_id", query.From.ID,
-		"chat_id", query.Message.Chat.ID)
+	callback := tgbotapi.NewCallback(query.ID, "")
+	if _, err := s.bot.Request(callback); err != nil {
+		logger.FromContext(ctx).Error("failed to acknowledge callback", "error", err)
+	}
 
-	schedule, err := s.trainService.GetSchedule(ctx, "8700", "4600")
-	if err != nil {
-		s.logger.Error("failed to get work route schedule", "error", err)
-		return fmt.Errorf("failed to get work route schedule: %w", err)
+	logger.FromContext(ctx).Info("handling callback query",
+		"action", data.action,
+		"payload", data.payload)
+
+	switch data.action {
+	case actionHome:
+		return s.handleHomeRoute(ctx, queryConversation(query), query.Message.MessageID)
+	case actionWork:
+		return s.handleWorkRoute(ctx, queryConversation(query), query.Message.MessageID)
+	case actionOther:
+		return s.handleOtherRoute(ctx, queryConversation(query), query.Message.MessageID)
+	case actionSearch:
+		return s.handleSearchTrain(ctx, query)
+	case actionRoute:
+		return s.handleRouteButton(ctx, query, data.payload)
+	case actionFavorite:
+		return s.handleFavoriteButton(ctx, query, data.payload)
+	case actionReverse, actionLater, actionAlert:
+		return s.handleResultShortcut(ctx, query, data)
+	default:
+		return s.handleStationSelection(ctx, query, data)
 	}
+}
 
-	s.logger.Info("work route schedule retrieved successfully",
-		"user_id", query.From.ID,
-		"chat_id", query.Message.Chat.ID)
+func (s *Service) handleHomeRoute(ctx context.Context, conv conversation, messageID int) error {
+	logger.FromContext(ctx).Info("handling home route request")
+	return s.showSchedule(ctx, conv, messageID, "4600", "8700", time.Time{})
+}
 
-	return s.SendMessages(ctx, query.Message.Chat.ID, schedule)
+func (s *Service) handleWorkRoute(ctx context.Context, conv conversation, messageID int) error {
+	logger.FromContext(ctx).Info("handling work route request")
+	return s.showSchedule(ctx, conv, messageID, "8700", "4600", time.Time{})
 }
 
-func (s *Service) handleOtherRoute(ctx context.Context, query *tgbotapi.CallbackQuery) error {
-	s.logger.Info("handling other route request",
-		"user_id", query.From.ID,
-		"chat_id", query.Message.Chat.ID)
+func (s *Service) handleOtherRoute(ctx context.Context, conv conversation, messageID int) error {
+	logger.FromContext(ctx).Info("handling other route request")
 
 	// Initialize the route selection process
 	route := make(map[string]string)
-	s.userState.Store(fmt.Sprintf("route_%d", query.Message.Chat.ID), route)
-	s.userState.Store(fmt.Sprintf("state_%d", query.Message.Chat.ID), "awaitingFromStation")
+	s.userState.Store(conv.routeKey(), route)
+	s.userState.Store(conv.stateKey(), "awaitingFromStation")
 
-	s.logger.Info("route selection started",
-		"user_id", query.From.ID,
-		"chat_id", query.Message.Chat.ID,
+	logger.FromContext(ctx).Info("route selection started",
 		"state", "awaitingFromStation")
 
-	return s.SendMessage(ctx, query.Message.Chat.ID,
+	return s.askForText(ctx, conv, messageID,
 		fmt.Sprintf("%s אנא הקלד את האותיות הראשונות של תחנת המוצא.", stationEmoji))
 }
 
 func (s *Service) handleSearchTrain(ctx context.Context, query *tgbotapi.CallbackQuery) error {
-	s.logger.Info("handling search train request",
-		"user_id", query.From.ID,
-		"chat_id", query.Message.Chat.ID)
+	logger.FromContext(ctx).Info("handling search train request")
 
-	route, _ := s.userState.Load(fmt.Sprintf("route_%d", query.Message.Chat.ID))
+	conv := queryConversation(query)
+	route, _ := s.userState.Load(conv.routeKey())
 	routeMap, ok := route.(map[string]string)
 	if !ok {
-		s.logger.Error("invalid route type",
-			"user_id", query.From.ID,
-			"chat_id", query.Message.Chat.ID,
-			"route", route)
+		logger.FromContext(ctx).Error("invalid route type",
+			"selected_route", route)
 		return fmt.Errorf("invalid route type")
 	}
+	// The selection is done; in groups the member's messages are chatter again
+	s.userState.Delete(conv.stateKey())
 
-	schedule, err := s.trainService.GetSchedule(ctx, routeMap["from"], routeMap["to"])
-	if err != nil {
-		s.logger.Error("failed to get schedule", "error", err)
-		return fmt.Errorf("failed to get schedule: %w", err)
-	}
-
-	s.logger.Info("train schedule retrieved successfully",
-		"user_id", query.From.ID,
-		"chat_id", query.Message.Chat.ID,
-		"to", routeMap["to"])
-
-	return s.SendMessages(ctx, query.Message.Chat.ID, schedule)
+	return s.showSchedule(ctx, conv, query.Message.MessageID, routeMap["from"], routeMap["to"], time.Time{})
 }
 
-func (s *Service) handleStationSelection(ctx context.Context, query *tgbotapi.CallbackQuery) error {
-	s.logger.Info("handling station selection",
-		"user_id", query.From.ID,
-		"chat_id", query.Message.Chat.ID,
-		"station_id", query.Data)
+func (s *Service) handleStationSelection(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackData) error {
+	stationID := data.payload
+	logger.FromContext(ctx).Info("handling station selection",
+		"station_id", stationID)
 
 	// Get the current state
-	state, _ := s.userState.Load(fmt.Sprintf("state_%d", query.Message.Chat.ID))
-	currentState, ok := state.(string)
-	if !ok {
-		s.logger.Error("invalid state type",
-			"user_id", query.From.ID,
-			"chat_id", query.Message.Chat.ID,
-			"state", state)
-		return s.SendMessage(ctx, query.Message.Chat.ID,
-			fmt.Sprintf("%s שגיאה במצב. אנא נסה שוב.", warningEmoji))
-	}
+	conv := queryConversation(query)
+	state, _ := s.userState.Load(conv.stateKey())
+	currentState, _ := state.(string)
 
-	s.logger.Info("current state",
-		"user_id", query.From.ID,
-		"chat_id", query.Message.Chat.ID,
+	logger.FromContext(ctx).Info("current state",
 		"state", currentState)
 
+	// A station button only applies to the step that showed it
+	if stationSelectionStates[data.action] != currentState {
+		logger.FromContext(ctx).Info("station button of another step pressed",
+			"action", data.action)
+		return s.restartFlow(ctx, conv, query.Message)
+	}
+
 	switch currentState {
+	case "clarifyingStation":
+		return s.handleStationClarification(ctx, query, stationID)
 	case "awaitingFromStationSelection":
 		// Get the route map
-		route, _ := s.userState.Load(fmt.Sprintf("route_%d", query.Message.Chat.ID))
+		route, _ := s.userState.Load(conv.routeKey())
 		routeMap, ok := route.(map[string]string)
 		if !ok {
-			s.logger.Error("invalid route type",
-				"user_id", query.From.ID,
-				"chat_id", query.Message.Chat.ID,
-				"route", route)
+			logger.FromContext(ctx).Error("invalid route type",
+				"selected_route", route)
 			return s.SendMessage(ctx, query.Message.Chat.ID,
 				fmt.Sprintf("%s שגיאה בבחירת התחנות. אנא נסה שוב.", warningEmoji))
 		}
-		routeMap["from"] = query.Data
+		routeMap["from"] = stationID
 		// Store the updated route map
-		s.userState.Store(fmt.Sprintf("route_%d", query.Message.Chat.ID), routeMap)
+		s.userState.Store(conv.routeKey(), routeMap)
 		// Set the state to awaitingToStation
-		s.userState.Store(fmt.Sprintf("state_%d", query.Message.Chat.ID), "awaitingToStation")
+		s.userState.Store(conv.stateKey(), "awaitingToStation")
 
-		s.logger.Info("from station selected, now awaiting to station input",
-			"user_id", query.From.ID,
-			"chat_id", query.Message.Chat.ID,
-			"station_id", query.Data)
+		logger.FromContext(ctx).Info("from station selected, now awaiting to station input",
+			"station_id", stationID)
 
-		return s.SendMessage(ctx, query.Message.Chat.ID,
-			fmt.Sprintf("%s אנא הקלד את האותיות הראשונות של תחנת היעד.", stationEmoji))
+		return s.askForText(ctx, conv, query.Message.MessageID,
+			fmt.Sprintf("%s מוצא: %s\n%s אנא הקלד את האותיות הראשונות של תחנת היעד.",
+				successEmoji, s.trainService.GetStationName(stationID), stationEmoji))
 	case "awaitingToStationSelection":
 		// Get the route map
-		route, _ := s.userState.Load(fmt.Sprintf("route_%d", query.Message.Chat.ID))
+		route, _ := s.userState.Load(conv.routeKey())
 		routeMap, ok := route.(map[string]string)
 		if !ok {
-			s.logger.Error("invalid route type",
-				"user_id", query.From.ID,
-				"chat_id", query.Message.Chat.ID,
-				"route", route)
+			logger.FromContext(ctx).Error("invalid route type",
+				"selected_route", route)
 			return s.SendMessage(ctx, query.Message.Chat.ID,
 				fmt.Sprintf("%s שגיאה בבחירת התחנות. אנא נסה שוב.", warningEmoji))
 		}
-		routeMap["to"] = query.Data
+		routeMap["to"] = stationID
 		// Store the updated route map
-		s.userState.Store(fmt.Sprintf("route_%d", query.Message.Chat.ID), routeMap)
+		s.userState.Store(conv.routeKey(), routeMap)
 
-		s.logger.Info("to station selected",
-			"user_id", query.From.ID,
-			"chat_id", query.Message.Chat.ID,
+		logger.FromContext(ctx).Info("to station selected",
 			"from_station", routeMap["from"],
 			"to_station", routeMap["to"])
 
-		btn := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("חפש %s", searchEmoji), "search_train")
-		inlineKeyboard := tgbotapi.NewInlineKeyboardMarkup([]tgbotapi.InlineKeyboardButton{btn})
-		msg := tgbotapi.NewMessage(query.Message.Chat.ID,
+		inlineKeyboard := newKeyboard().button(fmt.Sprintf("חפש %s", searchEmoji), s.callbacks.encode(actionSearch, "")).inlineMarkup()
+		return s.editMessage(ctx, query.Message.Chat.ID, query.Message.MessageID,
 			fmt.Sprintf("%s רכבת מתחנת %v לתחנת %v",
 				trainEmoji,
 				s.trainService.GetStationName(routeMap["from"]),
-				s.trainService.GetStationName(routeMap["to"])))
-		msg.ReplyMarkup = inlineKeyboard
-		_, err := s.bot.Send(msg)
-		return err
+				s.trainService.GetStationName(routeMap["to"])),
+			&inlineKeyboard)
 	default:
-		s.logger.Error("unknown state",
-			"user_id", query.From.ID,
-			"chat_id", query.Message.Chat.ID,
+		logger.FromContext(ctx).Error("unknown state",
 			"state", currentState)
 		return s.SendMessage(ctx, query.Message.Chat.ID,
 			fmt.Sprintf("%s מצב לא ידוע. אנא נסה שוב.", warningEmoji))
@@ -328,91 +493,73 @@ func (s *Service) handleStationSelection(ctx context.Context, query *tgbotapi.Ca
 }
 
 func (s *Service) handleAwaitingInput(ctx context.Context, msg *tgbotapi.Message) error {
-	s.logger.Info("handling awaiting input",
-		"user_id", msg.From.ID,
-		"chat_id", msg.Chat.ID,
-		"input", msg.Text)
+	logger.FromContext(ctx).Info("handling awaiting input")
 
 	// Get the current state
-	state, _ := s.userState.Load(fmt.Sprintf("state_%d", msg.Chat.ID))
+	conv := messageConversation(msg)
+	state, _ := s.userState.Load(conv.stateKey())
 	currentState, ok := state.(string)
 	if !ok {
-		s.logger.Error("invalid state type",
-			"user_id", msg.From.ID,
-			"chat_id", msg.Chat.ID,
+		logger.FromContext(ctx).Error("invalid state type",
 			"state", state)
 		return s.SendMessage(ctx, msg.Chat.ID,
 			fmt.Sprintf("%s שגיאה במצב. אנא נסה שוב.", warningEmoji))
 	}
 
-	s.logger.Info("current state",
-		"user_id", msg.From.ID,
-		"chat_id", msg.Chat.ID,
+	logger.FromContext(ctx).Info("current state",
 		"state", currentState)
 
 	switch currentState {
 	case "awaitingFromStation":
 		suggestions := s.trainService.GetStationSuggestions(msg.Text)
 		if len(suggestions) == 0 {
-			s.logger.Info("no stations found for input",
-				"user_id", msg.From.ID,
-				"chat_id", msg.Chat.ID,
-				"input", msg.Text)
+			logger.FromContext(ctx).Info("no stations found for input")
 			return s.SendMessage(ctx, msg.Chat.ID,
 				fmt.Sprintf("%s לא נמצאו תחנות תואמות. נסה להקליד אותיות אחרות.", warningEmoji))
 		}
 
-		var buttons [][]tgbotapi.InlineKeyboardButton
+		keyboard := newKeyboard()
 		for stationName, stationID := range suggestions {
-			btn := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s %s", stationEmoji, stationName), stationID)
-			buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(btn))
+			keyboard.button(fmt.Sprintf("%s %s", stationEmoji, stationName), s.callbacks.encode(actionFromStation, stationID))
 		}
 
-		inlineKeyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
 		message := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("%s אנא בחר תחנת מוצא:", stationEmoji))
-		message.ReplyMarkup = inlineKeyboard
-		_, err := s.bot.Send(message)
+		message.ReplyMarkup = keyboard.inlineMarkup()
+		_, err := s.send(ctx, msg.Chat.ID, message)
 		if err != nil {
-			s.logger.Error("failed to send message", "error", err)
+			logger.FromContext(ctx).Error("failed to send message", "error", err)
 			return fmt.Errorf("failed to send message: %w", err)
 		}
 
 		// Set the state to awaitingFromStationSelection
-		s.userState.Store(fmt.Sprintf("state_%d", msg.Chat.ID), "awaitingFromStationSelection")
+		s.userState.Store(conv.stateKey(), "awaitingFromStationSelection")
 		return nil
 	case "awaitingToStation":
 		suggestions := s.trainService.GetStationSuggestions(msg.Text)
 		if len(suggestions) == 0 {
-			s.logger.Info("no stations found for input",
-				"user_id", msg.From.ID,
-				"chat_id", msg.Chat.ID,
-				"input", msg.Text)
+			logger.FromContext(ctx).Info("no stations found for input")
 			return s.SendMessage(ctx, msg.Chat.ID,
 				fmt.Sprintf("%s לא נמצאו תחנות תואמות. נסה להקליד אותיות אחרות.", warningEmoji))
 		}
 
-		var buttons [][]tgbotapi.InlineKeyboardButton
+		keyboard := newKeyboard()
 		for stationName, stationID := range suggestions {
-			btn := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s %s", stationEmoji, stationName), stationID)
-			buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(btn))
+			keyboard.button(fmt.Sprintf("%s %s", stationEmoji, stationName), s.callbacks.encode(actionToStation, stationID))
 		}
 
-		inlineKeyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
 		message := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("%s אנא בחר תחנת יעד:", stationEmoji))
-		message.ReplyMarkup = inlineKeyboard
-		_, err := s.bot.Send(message)
+		message.ReplyMarkup = keyboard.inlineMarkup()
+		_, err := s.send(ctx, msg.Chat.ID, message)
 		if err != nil {
-			s.logger.Error("failed to send message", "error", err)
+			logger.FromContext(ctx).Error("failed to send message", "error", err)
 			return fmt.Errorf("failed to send message: %w", err)
 		}
 
 		// Set the state to awaitingToStationSelection
-		s.userState.Store(fmt.Sprintf("state_%d", msg.Chat.ID), "awaitingToStationSelection")
+		s.userState.Store(conv.stateKey(), "awaitingToStationSelection")
 		return nil
 	default:
-		s.logger.Error("unknown state",
-			"user_id", msg.From.ID,
-			"chat_id", msg.Chat.ID,
+		logger.FromContext(ctx).Error("unknown state",
 			"state", currentState)
 		return s.SendMessage(ctx, msg.Chat.ID,
 			fmt.Sprintf("%s מצב לא ידוע. אנא נסה שוב.", warningEmoji))
@@ -420,34 +567,36 @@ func (s *Service) handleAwaitingInput(ctx context.Context, msg *tgbotapi.Message
 }
 
 func (s *Service) handleDefaultState(ctx context.Context, msg *tgbotapi.Message) error {
-	s.logger.Info("handling default state",
-		"user_id", msg.From.ID,
-		"chat_id", msg.Chat.ID,
-		"text", msg.Text)
+	logger.FromContext(ctx).Info("handling default state")
+
+	if handled, err := s.handleMenuButton(ctx, msg); handled {
+		return err
+	}
 
 	switch msg.Text {
 	case trainEmoji:
-		keyboard := tgbotapi.NewInlineKeyboardMarkup(
-			tgbotapi.NewInlineKeyboardRow(
-				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("בית %s", homeEmoji), "home"),
-				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("עבודה %s", workEmoji), "work"),
-				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("אחר %s", searchEmoji), "other"),
-			),
-		)
-		msg := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("%s בחר יעד:", trainEmoji))
-		msg.ReplyMarkup = keyboard
-		_, err := s.bot.Send(msg)
-		return err
+		return s.sendRouteMenu(ctx, msg.Chat.ID, fmt.Sprintf("%s בחר יעד:", trainEmoji))
 	case steakEmoji:
 		return s.SendMessage(ctx, msg.Chat.ID, meat())
 	case cheeseEmoji:
 		return s.SendMessage(ctx, msg.Chat.ID, cheese())
 	default:
+		if handled, err := s.handleTripRequest(ctx, msg); handled {
+			return err
+		}
 		return s.SendMessage(ctx, msg.Chat.ID,
 			fmt.Sprintf("%s אנא בחר אופציה או הקלד את הפקודה הרצויה %s", warningEmoji, trainEmoji))
 	}
 }
 
+// sendRouteMenu offers the home, work and other routes
+func (s *Service) sendRouteMenu(ctx context.Context, chatID int64, text string) error {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = s.routeMenuKeyboard(chatID)
+	_, err := s.send(ctx, chatID, msg)
+	return err
+}
+
 func (s *Service) getStationSuggestions(query string) map[string]string {
 	// This is a placeholder. You should implement the actual station lookup logic
 	suggestions := make(map[string]string)
@@ -458,19 +607,37 @@ func (s *Service) getStationSuggestions(query string) map[string]string {
 
 func (s *Service) SendMessage(ctx context.Context, chatID int64, message string) error {
 	msg := tgbotapi.NewMessage(chatID, message)
-	_, err := s.bot.Send(msg)
+	_, err := s.send(ctx, chatID, msg)
 	return err
 }
 
 func (s *Service) SendMessages(ctx context.Context, chatID int64, messages []string) error {
+	_, err := s.sendMessages(ctx, chatID, 0, messages, nil)
+	return err
+}
+
+// sendMessages sends numbered parts, attaching markup to the last one, and
+// returns the ID of the last one. When replaceID is set the first part is
+// written into that message instead.
+func (s *Service) sendMessages(ctx context.Context, chatID int64, replaceID int, messages []string, markup *tgbotapi.InlineKeyboardMarkup) (int, error) {
+	lastID := 0
 	for i, message := range messages {
-		msg := tgbotapi.NewMessage(chatID, message)
 		if i > 0 {
-			msg.Text = fmt.Sprintf("(חלק %d מתוך %d)\n%s", i+1, len(messages), message)
+			message = fmt.Sprintf("(חלק %d מתוך %d)\n%s", i+1, len(messages), message)
 		}
-		if _, err := s.bot.Send(msg); err != nil {
-			return err
+		var partMarkup *tgbotapi.InlineKeyboardMarkup
+		if i == len(messages)-1 {
+			partMarkup = markup
+		}
+
+		messageID := 0
+		if i == 0 {
+			messageID = replaceID
+		}
+		var err error
+		if lastID, err = s.sendOrEdit(ctx, chatID, messageID, message, partMarkup); err != nil {
+			return 0, err
 		}
 	}
-	return nil
+	return lastID, nil
 }
@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// persistedState is the on-disk form of what the bot remembers about its chats
+type persistedState struct {
+	Profiles []persistedProfile `json:"profiles"`
+	// Conversation holds the userState entries: conversation states and
+	// routes being selected, keyed like "state_<chat>" and "route_<chat>", or
+	// "state_<chat>_<user>" for the members of a group
+	Conversation map[string]string            `json:"conversation,omitempty"`
+	Selections   map[string]map[string]string `json:"selections,omitempty"`
+}
+
+type persistedProfile struct {
+	ChatID         int64    `json:"chat_id"`
+	Routes         []route  `json:"routes,omitempty"`
+	Favorites      []route  `json:"favorites,omitempty"`
+	AlertRoutes    []route  `json:"alert_routes,omitempty"`
+	NotifiedAlerts []string `json:"notified_alerts,omitempty"`
+	AlertsMuted    bool     `json:"alerts_muted,omitempty"`
+	DefaultRoute   *route   `json:"default_route,omitempty"`
+}
+
+// SaveState writes the chat profiles and conversation states to path. The file is
+// replaced atomically so a crash never leaves a truncated state behind.
+func (s *Service) SaveState(path string) error {
+	state := persistedState{
+		Conversation: make(map[string]string),
+		Selections:   make(map[string]map[string]string),
+	}
+
+	s.profiles.Range(func(_, value any) bool {
+		p := value.(*userProfile)
+		p.mu.Lock()
+		saved := persistedProfile{
+			ChatID:       p.ChatID,
+			Routes:       append([]route(nil), p.Routes...),
+			Favorites:    append([]route(nil), p.Favorites...),
+			AlertRoutes:  append([]route(nil), p.AlertRoutes...),
+			AlertsMuted:  p.AlertsMuted,
+			DefaultRoute: p.DefaultRoute,
+		}
+		for id := range p.NotifiedAlerts {
+			saved.NotifiedAlerts = append(saved.NotifiedAlerts, id)
+		}
+		p.mu.Unlock()
+		sort.Strings(saved.NotifiedAlerts)
+		state.Profiles = append(state.Profiles, saved)
+		return true
+	})
+	sort.Slice(state.Profiles, func(i, j int) bool {
+		return state.Profiles[i].ChatID < state.Profiles[j].ChatID
+	})
+
+	s.userState.Range(func(key, value any) bool {
+		switch v := value.(type) {
+		case string:
+			state.Conversation[key.(string)] = v
+		case map[string]string:
+			state.Selections[key.(string)] = v
+		}
+		return true
+	})
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadState restores the state written by SaveState. A missing file is not an error.
+func (s *Service) LoadState(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var state persistedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return 0, fmt.Errorf("invalid state file %s: %w", path, err)
+	}
+
+	for _, saved := range state.Profiles {
+		p := s.profile(saved.ChatID)
+		p.mu.Lock()
+		p.Routes = saved.Routes
+		p.Favorites = saved.Favorites
+		p.AlertRoutes = saved.AlertRoutes
+		p.AlertsMuted = saved.AlertsMuted
+		p.DefaultRoute = saved.DefaultRoute
+		for _, id := range saved.NotifiedAlerts {
+			p.NotifiedAlerts[id] = true
+		}
+		p.mu.Unlock()
+	}
+	for key, value := range state.Conversation {
+		s.userState.Store(key, value)
+	}
+	for key, value := range state.Selections {
+		s.userState.Store(key, value)
+	}
+	return len(state.Profiles), nil
+}
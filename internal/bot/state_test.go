@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func newStateService() *Service {
+	return &Service{userState: &sync.Map{}, profiles: &sync.Map{}}
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	s := newStateService()
+	s.rememberRoute(1, "4600", "8700")
+	s.rememberRoute(1, "8700", "4600")
+	s.profile(2).NotifiedAlerts["a1"] = true
+	s.profile(2).AlertsMuted = true
+	s.profile(2).toggleFavorite(route{From: "3700", To: "4600"})
+	s.profile(2).toggleAlert(route{From: "4600", To: "3700"})
+	s.userState.Store("state_1", "awaitingToStation")
+	s.userState.Store("route_1", map[string]string{"from": "4600"})
+
+	if err := s.SaveState(path); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+
+	restored := newStateService()
+	count, err := restored.LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("LoadState() count = %d, want 2", count)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{
+			name: "Routes",
+			got:  restored.profile(1).savedRoutes(),
+			want: []route{{From: "8700", To: "4600"}, {From: "4600", To: "8700"}},
+		},
+		{
+			name: "Notified alerts",
+			got:  restored.profile(2).NotifiedAlerts,
+			want: map[string]bool{"a1": true},
+		},
+		{
+			name: "Favorites",
+			got:  restored.profile(2).favoriteRoutes(),
+			want: []route{{From: "3700", To: "4600"}},
+		},
+		{
+			name: "Alert routes",
+			got:  restored.profile(2).alertRoutes(),
+			want: []route{{From: "4600", To: "3700"}},
+		},
+		{
+			name: "Alerts muted",
+			got:  []bool{restored.profile(1).AlertsMuted, restored.profile(2).AlertsMuted},
+			want: []bool{false, true},
+		},
+		{
+			name: "Conversation state",
+			got:  load(restored, "state_1"),
+			want: "awaitingToStation",
+		},
+		{
+			name: "Route selection",
+			got:  load(restored, "route_1"),
+			want: map[string]string{"from": "4600"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+
+	if count, err := newStateService().LoadState(filepath.Join(t.TempDir(), "missing.json")); err != nil || count != 0 {
+		t.Errorf("LoadState() of a missing file = %d, %v", count, err)
+	}
+}
+
+func load(s *Service, key string) any {
+	value, _ := s.userState.Load(key)
+	return value
+}
@@ -0,0 +1,98 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"rail-go/internal/logger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TaskStatus describes the state of a scheduled task
+type TaskStatus struct {
+	ID        string
+	Paused    bool
+	LastRun   time.Time
+	NextRun   time.Time
+	LastError string
+}
+
+// TaskController gives the bot access to the scheduled tasks
+type TaskController interface {
+	Tasks() []TaskStatus
+	RunTask(taskID string) error
+	PauseTask(taskID string) error
+	ResumeTask(taskID string) error
+}
+
+// SetTaskController connects the bot to the scheduler for the task admin commands
+func (s *Service) SetTaskController(tasks TaskController) {
+	s.tasks = tasks
+}
+
+// handleTasks lists the scheduled tasks with their last and next runs
+func (s *Service) handleTasks(ctx context.Context, msg *tgbotapi.Message) error {
+	if s.tasks == nil {
+		return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("%s המתזמן אינו פעיל.", warningEmoji))
+	}
+
+	statuses := s.tasks.Tasks()
+	if len(statuses) == 0 {
+		return s.SendMessage(ctx, msg.Chat.ID, "אין משימות מתוזמנות.")
+	}
+
+	var text strings.Builder
+	for _, status := range statuses {
+		state := "▶️"
+		if status.Paused {
+			state = "⏸"
+		}
+		fmt.Fprintf(&text, "%s %s\n", state, status.ID)
+		fmt.Fprintf(&text, "  ריצה אחרונה: %s\n", formatTaskTime(status.LastRun))
+		fmt.Fprintf(&text, "  ריצה הבאה: %s\n", formatTaskTime(status.NextRun))
+		if status.LastError != "" {
+			fmt.Fprintf(&text, "  %s שגיאה אחרונה: %s\n", warningEmoji, status.LastError)
+		}
+		text.WriteString("\n")
+	}
+
+	return s.SendMessage(ctx, msg.Chat.ID, text.String())
+}
+
+// handleTaskCommand handles "/task <run|pause|resume> <id>"
+func (s *Service) handleTaskCommand(ctx context.Context, msg *tgbotapi.Message, action, taskID string) error {
+	if s.tasks == nil {
+		return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("%s המתזמן אינו פעיל.", warningEmoji))
+	}
+
+	var err error
+	switch action {
+	case "run":
+		err = s.tasks.RunTask(taskID)
+	case "pause":
+		err = s.tasks.PauseTask(taskID)
+	case "resume":
+		err = s.tasks.ResumeTask(taskID)
+	default:
+		return s.SendMessage(ctx, msg.Chat.ID, "שימוש: /task <run|pause|resume> <id>")
+	}
+	if err != nil {
+		return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("%s %v", warningEmoji, err))
+	}
+
+	logger.FromContext(ctx).Info("task command executed",
+		"action", action,
+		"task_id", taskID)
+
+	return s.SendMessage(ctx, msg.Chat.ID, fmt.Sprintf("%s %s %s", successEmoji, action, taskID))
+}
+
+func formatTaskTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("02/01 15:04:05")
+}
@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sync"
+	"testing"
+
+	"rail-go/internal/logger"
+	"rail-go/internal/train"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// telegramCall is a Bot API request received by fakeTelegram
+type telegramCall struct {
+	method string
+	params url.Values
+}
+
+// fakeTelegram answers every Bot API method and records the requests
+type fakeTelegram struct {
+	mu     sync.Mutex
+	calls  []telegramCall
+	nextID int
+	// throttled is how many of the next requests are answered with a 429
+	throttled int
+}
+
+func (f *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	method := path.Base(r.URL.Path)
+
+	w.Header().Set("Content-Type", "application/json")
+	if method == "getMe" {
+		w.Write([]byte(`{"ok": true, "result": {"id": 1, "is_bot": true, "username": "rail_bot"}}`))
+		return
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, telegramCall{method: method, params: r.PostForm})
+	if f.throttled > 0 {
+		f.throttled--
+		f.mu.Unlock()
+		w.Write([]byte(`{"ok": false, "error_code": 429, "description": "Too Many Requests: retry after 1", "parameters": {"retry_after": 1}}`))
+		return
+	}
+	f.nextID++
+	id := f.nextID
+	f.mu.Unlock()
+
+	fmt.Fprintf(w, `{"ok": true, "result": {"message_id": %d, "chat": {"id": %s}}}`, id, r.PostForm.Get("chat_id"))
+}
+
+// requests returns the recorded calls, leaving out callback acknowledgements
+func (f *fakeTelegram) requests() []telegramCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var calls []telegramCall
+	for _, call := range f.calls {
+		if call.method != "answerCallbackQuery" {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// newTestService returns a bot talking to a fake Telegram server and a train
+// service answering every search with one direct train
+func newTestService(t *testing.T) (*Service, *fakeTelegram) {
+	t.Helper()
+
+	rail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result": {"travels": [
+			{"trains": [{"orignStation": 4600, "destinationStation": 8700, "departureTime": "2025-05-04T08:05:00", "arrivalTime": "2025-05-04T08:35:00", "originPlatform": 1, "destPlatform": 2}]}
+		]}}`))
+	}))
+	t.Cleanup(rail.Close)
+
+	telegram := &fakeTelegram{}
+	server := httptest.NewServer(telegram)
+	t.Cleanup(server.Close)
+
+	endpoint := server.URL + "/bot%s/%s"
+	bot, err := tgbotapi.NewBotAPIWithClient("test_token", endpoint, server.Client())
+	if err != nil {
+		t.Fatalf("NewBotAPIWithClient() error = %v", err)
+	}
+
+	cfg := train.NewTestConfig()
+	cfg.Train.BaseURL = rail.URL
+	s := newService(cfg, bot, logger.New())
+	s.apiEndpoint = endpoint
+	return s, telegram
+}
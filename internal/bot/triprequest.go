@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"rail-go/internal/logger"
+	"rail-go/internal/train"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleTripRequest answers a free text route like "מהרצליה לתל אביב השלום ב-8"
+// in one step. It reports false when the text is not a route.
+func (s *Service) handleTripRequest(ctx context.Context, msg *tgbotapi.Message) (bool, error) {
+	req, err := train.ParseTripRequest(msg.Text, time.Now())
+	if errors.Is(err, train.ErrUnknownStation) {
+		return false, nil
+	}
+	if err != nil {
+		return true, s.SendMessage(ctx, msg.Chat.ID,
+			fmt.Sprintf("%s השעה לא תקינה. נסה למשל: הרצליה השלום 08:30", warningEmoji))
+	}
+
+	logger.FromContext(ctx).Info("trip request parsed",
+		"from", req.From,
+		"to", req.To,
+		"at", req.At.Format(time.RFC3339),
+		"ambiguous", req.Ambiguous())
+
+	if !req.Ambiguous() {
+		return true, s.showSchedule(ctx, messageConversation(msg), 0, req.From, req.To, req.At)
+	}
+
+	// Keep what is known and ask about the ambiguous stations one at a time
+	route := map[string]string{
+		"from": req.From,
+		"to":   req.To,
+		"at":   req.At.Format(time.RFC3339),
+	}
+	if req.From == "" {
+		route["from_query"] = req.FromQuery
+		route["from_candidates"] = stationIDs(req.FromCandidates)
+	}
+	if req.To == "" {
+		route["to_query"] = req.ToQuery
+		route["to_candidates"] = stationIDs(req.ToCandidates)
+	}
+	conv := messageConversation(msg)
+	s.userState.Store(conv.routeKey(), route)
+	return true, s.askNextStation(ctx, conv, 0, route)
+}
+
+// askNextStation asks which station was meant for the first ambiguous end of the
+// route, or searches once both ends are known. It edits the previous question
+// when messageID is set.
+func (s *Service) askNextStation(ctx context.Context, conv conversation, messageID int, route map[string]string) error {
+	side := "from"
+	if route["from"] != "" {
+		side = "to"
+	}
+	if route[side] != "" {
+		s.userState.Delete(conv.stateKey())
+		at, err := time.Parse(time.RFC3339, route["at"])
+		if err != nil {
+			at = time.Now()
+		}
+		return s.showSchedule(ctx, conv, messageID, route["from"], route["to"], at)
+	}
+
+	keyboard := newKeyboard()
+	for _, id := range strings.Split(route[side+"_candidates"], ",") {
+		keyboard.button(fmt.Sprintf("%s %s", stationEmoji, s.trainService.GetStationName(id)), s.callbacks.encode(actionClarify, id))
+	}
+
+	s.userState.Store(conv.stateKey(), "clarifyingStation")
+	markup := keyboard.inlineMarkup()
+	_, err := s.sendOrEdit(ctx, conv.chatID, messageID,
+		fmt.Sprintf("%s לאיזו תחנה התכוונת ב\"%s\"?", stationEmoji, route[side+"_query"]), &markup)
+	return err
+}
+
+// handleStationClarification stores the station picked for an ambiguous name
+func (s *Service) handleStationClarification(ctx context.Context, query *tgbotapi.CallbackQuery, stationID string) error {
+	chatID := query.Message.Chat.ID
+	conv := queryConversation(query)
+	value, _ := s.userState.Load(conv.routeKey())
+	route, ok := value.(map[string]string)
+	if !ok {
+		return s.SendMessage(ctx, chatID,
+			fmt.Sprintf("%s שגיאה בבחירת התחנות. אנא נסה שוב.", warningEmoji))
+	}
+
+	side := "from"
+	if route["from"] != "" {
+		side = "to"
+	}
+	if !slices.Contains(strings.Split(route[side+"_candidates"], ","), stationID) {
+		logger.FromContext(ctx).Info("station not among candidates", "station_id", stationID)
+		return s.SendMessage(ctx, chatID,
+			fmt.Sprintf("%s אנא בחר אחת מהתחנות המוצעות.", warningEmoji))
+	}
+	route[side] = stationID
+	s.userState.Store(conv.routeKey(), route)
+
+	logger.FromContext(ctx).Info("ambiguous station clarified",
+		"side", side,
+		"station_id", stationID)
+	return s.askNextStation(ctx, conv, query.Message.MessageID, route)
+}
+
+func stationIDs(stations []train.Station) string {
+	ids := make([]string, len(stations))
+	for i, station := range stations {
+		ids[i] = station.ID
+	}
+	return strings.Join(ids, ",")
+}
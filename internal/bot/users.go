@@ -0,0 +1,143 @@
+package bot
+
+import (
+	"slices"
+	"sync"
+)
+
+const (
+	// maxSavedRoutes is how many recent searches a chat keeps
+	maxSavedRoutes = 5
+	maxFavorites   = 10
+	maxAlertRoutes = 10
+)
+
+// route is a pair of station IDs
+type route struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// userProfile holds what the bot remembers about a chat between searches
+type userProfile struct {
+	mu             sync.Mutex
+	ChatID         int64
+	Routes         []route
+	NotifiedAlerts map[string]bool
+	// AlertsMuted stops the automatic disruption notices; /alerts still works
+	AlertsMuted bool
+	// Favorites are the starred routes, in the order they were starred
+	Favorites []route
+	// AlertRoutes always get disruption notices, even when AlertsMuted is set
+	AlertRoutes []route
+	// DefaultRoute is the shared route of a group, set with /setroute
+	DefaultRoute *route
+}
+
+// profile returns the profile of the given chat, creating it on first use
+func (s *Service) profile(chatID int64) *userProfile {
+	p, _ := s.profiles.LoadOrStore(chatID, &userProfile{
+		ChatID:         chatID,
+		NotifiedAlerts: make(map[string]bool),
+	})
+	return p.(*userProfile)
+}
+
+// rememberRoute saves a searched route in the chat profile, most recent first
+func (s *Service) rememberRoute(chatID int64, from, to string) {
+	p := s.profile(chatID)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	routes := []route{{From: from, To: to}}
+	for _, r := range p.Routes {
+		if r.From == from && r.To == to {
+			continue
+		}
+		routes = append(routes, r)
+	}
+	if len(routes) > maxSavedRoutes {
+		routes = routes[:maxSavedRoutes]
+	}
+	p.Routes = routes
+}
+
+// savedRoutes returns a copy of the routes saved for the chat
+func (p *userProfile) savedRoutes() []route {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]route(nil), p.Routes...)
+}
+
+// favoriteRoutes returns a copy of the starred routes
+func (p *userProfile) favoriteRoutes() []route {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]route(nil), p.Favorites...)
+}
+
+func (p *userProfile) isFavorite(r route) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Contains(p.Favorites, r)
+}
+
+// toggleFavorite stars or unstars a route and reports whether it is now a
+// favorite. It fails when starring would exceed maxFavorites.
+func (p *userProfile) toggleFavorite(r route) (favorite, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return toggleRoute(&p.Favorites, r, maxFavorites)
+}
+
+// alertRoutes returns a copy of the routes with a disruption alert
+func (p *userProfile) alertRoutes() []route {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]route(nil), p.AlertRoutes...)
+}
+
+func (p *userProfile) hasAlert(r route) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Contains(p.AlertRoutes, r)
+}
+
+// toggleAlert sets or removes the disruption alert of a route and reports
+// whether it is now set. It fails when setting would exceed maxAlertRoutes.
+func (p *userProfile) toggleAlert(r route) (set, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return toggleRoute(&p.AlertRoutes, r, maxAlertRoutes)
+}
+
+// defaultRoute returns the route set with /setroute, if any
+func (p *userProfile) defaultRoute() (route, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.DefaultRoute == nil {
+		return route{}, false
+	}
+	return *p.DefaultRoute, true
+}
+
+// setDefaultRoute sets the route of /route, or removes it when r is nil
+func (p *userProfile) setDefaultRoute(r *route) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.DefaultRoute = r
+}
+
+// toggleRoute adds r to routes, or removes it when present. Adding fails when
+// routes already holds max routes.
+func toggleRoute(routes *[]route, r route, max int) (added, ok bool) {
+	if i := slices.Index(*routes, r); i >= 0 {
+		*routes = slices.Delete(*routes, i, i+1)
+		return false, true
+	}
+	if len(*routes) >= max {
+		return false, false
+	}
+	*routes = append(*routes, r)
+	return true, true
+}
@@ -1,39 +1,126 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+
+	"rail-go/internal/secrets"
 )
 
+// defaultConfigFile is read when CONFIG_FILE is not set and the file exists
+const defaultConfigFile = "config.yaml"
+
 type Config struct {
-	Version string
+	Version string `yaml:"version"`
 	Bot     struct {
-		Token       string
-		Debug       bool
-		Timeout     int
-		AdminChatID int64
-	}
+		Token       string  `yaml:"-"` // secret
+		Debug       bool    `yaml:"debug"`
+		Timeout     int     `yaml:"timeout"`
+		AdminChatID int64   `yaml:"admin_chat_id"`
+		AdminIDs    []int64 `yaml:"admin_ids"`
+		AllowIDs    []int64 `yaml:"allow_ids"`
+		DenyIDs     []int64 `yaml:"deny_ids"`
+		// StateFile persists chat profiles across restarts (empty disables)
+		StateFile       string        `yaml:"state_file"`
+		ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+		// CallbackSecret signs the data of inline buttons (empty disables signing)
+		CallbackSecret string `yaml:"-"` // secret
+	} `yaml:"bot"`
+	Log struct {
+		Level      string   `yaml:"level"`
+		Encoding   string   `yaml:"format"`
+		Sampling   bool     `yaml:"sampling"`
+		Outputs    []string `yaml:"outputs"`
+		File       string   `yaml:"file"`
+		MaxSizeMB  int      `yaml:"max_size_mb"`
+		MaxBackups int      `yaml:"max_backups"`
+		MaxAgeDays int      `yaml:"max_age_days"`
+		// Privacy enables data minimization: hashed IDs and truncated user text
+		Privacy       bool   `yaml:"privacy"`
+		HashSalt      string `yaml:"-"` // secret
+		MaxTextLength int    `yaml:"max_text_length"`
+	} `yaml:"log"`
+	HTTP struct {
+		Addr string `yaml:"addr"`
+	} `yaml:"http"`
+	// API is the REST API served under /v1 on the HTTP server
+	API struct {
+		Keys []string `yaml:"-"` // secret; the API is disabled without keys
+	} `yaml:"-"`
+	Tracing struct {
+		Exporter    string `yaml:"exporter"`
+		ServiceName string `yaml:"service_name"`
+	} `yaml:"tracing"`
+	RateLimit struct {
+		UserPerMinute   int `yaml:"user_per_minute"`
+		UserBurst       int `yaml:"user_burst"`
+		GlobalPerMinute int `yaml:"global_per_minute"`
+		GlobalBurst     int `yaml:"global_burst"`
+		// InlinePerMinute and InlineBurst are the budget of inline queries per
+		// user, looser than the user limit since Telegram sends one per keystroke
+		InlinePerMinute int `yaml:"inline_per_minute"`
+		InlineBurst     int `yaml:"inline_burst"`
+		// SendPerSecond, ChatSendPerMinute and GroupSendPerMinute pace the
+		// messages the bot sends, within Telegram's limits
+		SendPerSecond      int `yaml:"send_per_second"`
+		ChatSendPerMinute  int `yaml:"chat_send_per_minute"`
+		GroupSendPerMinute int `yaml:"group_send_per_minute"`
+	} `yaml:"rate_limit"`
 	Scheduler struct {
-		DefaultInterval time.Duration
-		RetryAttempts   int
-	}
+		DefaultInterval time.Duration `yaml:"default_interval"`
+		RetryAttempts   int           `yaml:"retry_attempts"`
+	} `yaml:"scheduler"`
 	Notifications struct {
-		DefaultChatID int64
-		TimeFormat    string
-		Templates     map[string]string
-	}
+		DefaultChatID  int64             `yaml:"default_chat_id"`
+		TimeFormat     string            `yaml:"time_format"`
+		Templates      map[string]string `yaml:"templates"`
+		AlertsInterval time.Duration     `yaml:"alerts_interval"`
+		Jobs           []NotificationJob `yaml:"jobs"`
+	} `yaml:"notifications"`
 	Train struct {
-		APIKey    string
-		UserAgent string
-		BaseURL   string
-		Timeout   time.Duration
-	}
+		APIKey       string        `yaml:"-"` // secret
+		UserAgent    string        `yaml:"user_agent"`
+		BaseURL      string        `yaml:"base_url"`
+		UpdatesURL   string        `yaml:"updates_url"`
+		StationsFile string        `yaml:"stations_file"`
+		Timeout      time.Duration `yaml:"timeout"`
+	} `yaml:"train"`
 }
 
+// AlertsTaskID is the scheduler task of the disruption alerts, which notification
+// jobs must not take
+const AlertsTaskID = "alerts_push"
+
+// NotificationJob is a scheduled message rendered from a text/template
+type NotificationJob struct {
+	ID       string  `json:"id" yaml:"id"`
+	Schedule string  `json:"schedule" yaml:"schedule"`
+	ChatIDs  []int64 `json:"chat_ids" yaml:"chat_ids"`
+	Template string  `json:"template" yaml:"template"`
+}
+
+// Load builds the configuration from defaults, then the config file (CONFIG_FILE or
+// config.yaml), then environment variables, and validates the result.
 func Load() (*Config, error) {
+	return load(true)
+}
+
+// LoadOffline loads the configuration for tools that do not talk to Telegram,
+// so the bot token is not required.
+func LoadOffline() (*Config, error) {
+	return load(false)
+}
+
+func load(requireBot bool) (*Config, error) {
 	// Load .env file
 	if err := godotenv.Load(); err != nil {
 		// It's okay if .env file doesn't exist
@@ -42,68 +129,313 @@ func Load() (*Config, error) {
 		}
 	}
 
+	cfg := defaults()
+
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		if _, err := os.Stat(defaultConfigFile); err == nil {
+			path = defaultConfigFile
+		}
+	}
+	if path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// Report malformed environment values together with validation
+	// failures so a broken deployment can be fixed in one pass.
+	if err := errors.Join(applyEnv(cfg), cfg.validate(requireBot)); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func defaults() *Config {
 	cfg := &Config{}
 
+	cfg.Version = "1.0.0"
+
+	cfg.Bot.Timeout = 60
+	cfg.Bot.ShutdownTimeout = 15 * time.Second
+
+	cfg.Log.Level = "info"
+	cfg.Log.Encoding = "json"
+	cfg.Log.Sampling = true
+	cfg.Log.Outputs = []string{"stdout"}
+	cfg.Log.MaxSizeMB = 100
+	cfg.Log.MaxBackups = 5
+	cfg.Log.MaxAgeDays = 30
+
+	cfg.HTTP.Addr = ":8080"
+
+	cfg.Tracing.ServiceName = "rail-go"
+
+	cfg.RateLimit.UserPerMinute = 20
+	cfg.RateLimit.UserBurst = 5
+	cfg.RateLimit.GlobalPerMinute = 600
+	cfg.RateLimit.GlobalBurst = 30
+	cfg.RateLimit.InlinePerMinute = 120
+	cfg.RateLimit.InlineBurst = 20
+	cfg.RateLimit.SendPerSecond = 30
+	cfg.RateLimit.ChatSendPerMinute = 60
+	cfg.RateLimit.GroupSendPerMinute = 20
+
+	cfg.Scheduler.DefaultInterval = time.Hour * 24 * 30 // Default to monthly
+	cfg.Scheduler.RetryAttempts = 3
+
+	cfg.Notifications.TimeFormat = "Mon Jan 2 15:04:05"
+	cfg.Notifications.Templates = map[string]string{
+		"monthly": "need to use sibus",
+	}
+	cfg.Notifications.AlertsInterval = 15 * time.Minute
+
+	cfg.Train.UserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15"
+	cfg.Train.BaseURL = "https://israelrail.azurefd.net/rjpa-prod/api/v1"
+	cfg.Train.UpdatesURL = "https://israelrail.azurefd.net/common/api/v1/GetUpdates"
+	cfg.Train.Timeout = 10 * time.Second
+
+	return cfg
+}
+
+// loadFile overlays the YAML (or JSON) config file on cfg
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// secretsProvider supplies credentials. They are never read from the config file.
+var secretsProvider = secrets.Default()
+
+// applyEnv overlays environment variables and secrets on cfg. Every malformed value is reported.
+func applyEnv(cfg *Config) error {
+	env := &envLoader{secrets: secretsProvider}
+
 	// Version
-	cfg.Version = getEnvOrDefault("APP_VERSION", "1.0.0")
+	cfg.Version = env.String("APP_VERSION", cfg.Version)
 
 	// Bot configuration
-	cfg.Bot.Token = getEnvOrDefault("BOT_TOKEN", "")
-	cfg.Bot.Debug = getEnvBoolOrDefault("BOT_DEBUG", false)
-	cfg.Bot.Timeout = getEnvIntOrDefault("BOT_TIMEOUT", 60)
-	adminChatID, err := strconv.ParseInt(getEnvOrDefault("BOT_ADMIN_CHAT_ID", "0"), 10, 64)
-	if err != nil {
-		return nil, err
+	cfg.Bot.Token = env.Secret("BOT_TOKEN")
+	cfg.Bot.CallbackSecret = env.Secret("BOT_CALLBACK_SECRET")
+	cfg.Bot.Debug = env.Bool("BOT_DEBUG", cfg.Bot.Debug)
+	cfg.Bot.Timeout = env.Int("BOT_TIMEOUT", cfg.Bot.Timeout)
+	cfg.Bot.AdminChatID = env.Int64("BOT_ADMIN_CHAT_ID", cfg.Bot.AdminChatID)
+	cfg.Bot.AdminIDs = env.Int64List("BOT_ADMIN_IDS", cfg.Bot.AdminIDs)
+	if cfg.Bot.AdminChatID != 0 {
+		cfg.Bot.AdminIDs = append(cfg.Bot.AdminIDs, cfg.Bot.AdminChatID)
 	}
-	cfg.Bot.AdminChatID = adminChatID
+	cfg.Bot.AllowIDs = env.Int64List("BOT_ALLOW_IDS", cfg.Bot.AllowIDs)
+	cfg.Bot.DenyIDs = env.Int64List("BOT_DENY_IDS", cfg.Bot.DenyIDs)
+	cfg.Bot.StateFile = env.String("BOT_STATE_FILE", cfg.Bot.StateFile)
+	cfg.Bot.ShutdownTimeout = env.Duration("BOT_SHUTDOWN_TIMEOUT", time.Second, cfg.Bot.ShutdownTimeout)
+
+	// Log configuration
+	cfg.Log.Level = env.String("LOG_LEVEL", cfg.Log.Level)
+	cfg.Log.Encoding = env.String("LOG_FORMAT", cfg.Log.Encoding)
+	cfg.Log.Sampling = env.Bool("LOG_SAMPLING", cfg.Log.Sampling)
+	cfg.Log.Outputs = env.List("LOG_OUTPUT", cfg.Log.Outputs)
+	cfg.Log.File = env.String("LOG_FILE", cfg.Log.File)
+	cfg.Log.MaxSizeMB = env.Int("LOG_MAX_SIZE_MB", cfg.Log.MaxSizeMB)
+	cfg.Log.MaxBackups = env.Int("LOG_MAX_BACKUPS", cfg.Log.MaxBackups)
+	cfg.Log.MaxAgeDays = env.Int("LOG_MAX_AGE_DAYS", cfg.Log.MaxAgeDays)
+	cfg.Log.Privacy = env.Bool("LOG_PRIVACY", cfg.Log.Privacy)
+	cfg.Log.HashSalt = env.Secret("LOG_HASH_SALT")
+	cfg.Log.MaxTextLength = env.Int("LOG_MAX_TEXT_LENGTH", cfg.Log.MaxTextLength)
+
+	// HTTP server configuration
+	cfg.HTTP.Addr = env.String("HTTP_ADDR", cfg.HTTP.Addr)
+
+	// REST API configuration
+	cfg.API.Keys = env.SecretList("API_KEYS")
+
+	// Tracing configuration
+	cfg.Tracing.Exporter = env.String("TRACING_EXPORTER", cfg.Tracing.Exporter)
+	cfg.Tracing.ServiceName = env.String("TRACING_SERVICE_NAME", cfg.Tracing.ServiceName)
+
+	// Rate limit configuration
+	cfg.RateLimit.UserPerMinute = env.Int("RATE_LIMIT_USER_PER_MINUTE", cfg.RateLimit.UserPerMinute)
+	cfg.RateLimit.UserBurst = env.Int("RATE_LIMIT_USER_BURST", cfg.RateLimit.UserBurst)
+	cfg.RateLimit.GlobalPerMinute = env.Int("RATE_LIMIT_GLOBAL_PER_MINUTE", cfg.RateLimit.GlobalPerMinute)
+	cfg.RateLimit.GlobalBurst = env.Int("RATE_LIMIT_GLOBAL_BURST", cfg.RateLimit.GlobalBurst)
+	cfg.RateLimit.InlinePerMinute = env.Int("RATE_LIMIT_INLINE_PER_MINUTE", cfg.RateLimit.InlinePerMinute)
+	cfg.RateLimit.InlineBurst = env.Int("RATE_LIMIT_INLINE_BURST", cfg.RateLimit.InlineBurst)
+	cfg.RateLimit.SendPerSecond = env.Int("RATE_LIMIT_SEND_PER_SECOND", cfg.RateLimit.SendPerSecond)
+	cfg.RateLimit.ChatSendPerMinute = env.Int("RATE_LIMIT_CHAT_SEND_PER_MINUTE", cfg.RateLimit.ChatSendPerMinute)
+	cfg.RateLimit.GroupSendPerMinute = env.Int("RATE_LIMIT_GROUP_SEND_PER_MINUTE", cfg.RateLimit.GroupSendPerMinute)
 
 	// Scheduler configuration
-	cfg.Scheduler.DefaultInterval = time.Hour * 24 * 30 // Default to monthly
-	cfg.Scheduler.RetryAttempts = getEnvIntOrDefault("SCHEDULER_RETRY_ATTEMPTS", 3)
+	cfg.Scheduler.RetryAttempts = env.Int("SCHEDULER_RETRY_ATTEMPTS", cfg.Scheduler.RetryAttempts)
 
 	// Notifications configuration
-	chatID, err := strconv.ParseInt(getEnvOrDefault("DEFAULT_CHAT_ID", "0"), 10, 64)
-	if err != nil {
-		return nil, err
+	cfg.Notifications.DefaultChatID = env.Int64("DEFAULT_CHAT_ID", cfg.Notifications.DefaultChatID)
+	cfg.Notifications.TimeFormat = env.String("TIME_FORMAT", cfg.Notifications.TimeFormat)
+	// A null templates entry in the file leaves the map nil
+	if cfg.Notifications.Templates == nil {
+		cfg.Notifications.Templates = map[string]string{}
 	}
-	cfg.Notifications.DefaultChatID = chatID
-	cfg.Notifications.TimeFormat = getEnvOrDefault("TIME_FORMAT", "Mon Jan 2 15:04:05")
-	cfg.Notifications.Templates = map[string]string{
-		"monthly": getEnvOrDefault("MONTHLY_TEMPLATE", "need to use sibus"),
+	cfg.Notifications.Templates["monthly"] = env.String("MONTHLY_TEMPLATE", cfg.Notifications.Templates["monthly"])
+	cfg.Notifications.AlertsInterval = env.Duration("ALERTS_INTERVAL", time.Minute, cfg.Notifications.AlertsInterval)
+	if value := os.Getenv("NOTIFICATION_JOBS"); value != "" {
+		var jobs []NotificationJob
+		if err := json.Unmarshal([]byte(value), &jobs); err != nil {
+			env.errs = append(env.errs, fmt.Errorf("invalid NOTIFICATION_JOBS: %w", err))
+		}
+		cfg.Notifications.Jobs = jobs
+	}
+	if len(cfg.Notifications.Jobs) == 0 && cfg.Notifications.DefaultChatID != 0 {
+		cfg.Notifications.Jobs = []NotificationJob{{
+			ID:       "monthly_notification",
+			Schedule: "monthly",
+			ChatIDs:  []int64{cfg.Notifications.DefaultChatID},
+			Template: "{{formatTime .Now}} " + cfg.Notifications.Templates["monthly"],
+		}}
 	}
 
 	// Train service configuration
-	cfg.Train.APIKey = getEnvOrDefault("TRAIN_API_KEY", "")
-	cfg.Train.UserAgent = getEnvOrDefault("TRAIN_USER_AGENT",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15")
-	cfg.Train.BaseURL = getEnvOrDefault("TRAIN_BASE_URL",
-		"https://israelrail.azurefd.net/rjpa-prod/api/v1")
-	cfg.Train.Timeout = time.Duration(getEnvIntOrDefault("TRAIN_TIMEOUT", 10)) * time.Second
+	cfg.Train.APIKey = env.Secret("TRAIN_API_KEY")
+	cfg.Train.UserAgent = env.String("TRAIN_USER_AGENT", cfg.Train.UserAgent)
+	cfg.Train.BaseURL = env.String("TRAIN_BASE_URL", cfg.Train.BaseURL)
+	cfg.Train.UpdatesURL = env.String("TRAIN_UPDATES_URL", cfg.Train.UpdatesURL)
+	cfg.Train.StationsFile = env.String("TRAIN_STATIONS_FILE", cfg.Train.StationsFile)
+	cfg.Train.Timeout = env.Duration("TRAIN_TIMEOUT", time.Second, cfg.Train.Timeout)
 
-	return cfg, nil
+	return errors.Join(env.errs...)
+}
+
+// envLoader reads typed environment variables, collecting parse errors instead of
+// silently falling back to the default
+type envLoader struct {
+	secrets secrets.Provider
+	errs    []error
 }
 
-func getEnvOrDefault(key, defaultValue string) string {
+// SecretList reads a comma-separated list of credentials
+func (e *envLoader) SecretList(key string) []string {
+	var result []string
+	for _, item := range strings.Split(e.Secret(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// Secret reads a credential from the secrets provider. A missing secret is left
+// empty for Validate to report.
+func (e *envLoader) Secret(key string) string {
+	value, err := e.secrets.Lookup(key)
+	if err != nil && !errors.Is(err, secrets.ErrNotFound) {
+		e.errs = append(e.errs, fmt.Errorf("invalid %s: %w", key, err))
+	}
+	return value
+}
+
+func (e *envLoader) String(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
 
-func getEnvIntOrDefault(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+func (e *envLoader) Int(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("invalid %s %q: must be an integer", key, value))
+		return defaultValue
+	}
+	return intValue
+}
+
+func (e *envLoader) Int64(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	intValue, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("invalid %s %q: must be an integer", key, value))
+		return defaultValue
+	}
+	return intValue
+}
+
+func (e *envLoader) Bool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("invalid %s %q: must be true or false", key, value))
+		return defaultValue
+	}
+	return boolValue
+}
+
+// Duration reads a plain number in the given unit (e.g. "10" seconds) or a Go duration ("10s")
+func (e *envLoader) Duration(key string, unit time.Duration, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		return time.Duration(n) * unit
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("invalid %s %q: must be a number or a duration", key, value))
+		return defaultValue
+	}
+	return duration
+}
+
+func (e *envLoader) List(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
 		}
 	}
-	return defaultValue
+	return result
 }
 
-func getEnvBoolOrDefault(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
-		if boolValue, err := strconv.ParseBool(value); err == nil {
-			return boolValue
+func (e *envLoader) Int64List(key string, defaultValue []int64) []int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var result []int64
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(item, 10, 64)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("invalid %s item %q: must be an integer", key, item))
+			continue
 		}
+		result = append(result, id)
 	}
-	return defaultValue
+	return result
 }
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+bot:
+  admin_ids: [1, 2]
+rate_limit:
+  user_per_minute: 10
+train:
+  timeout: 5s
+notifications:
+  jobs:
+    - id: morning
+      schedule: daily 07:30
+      chat_ids: [42]
+      template: "{{formatTime .Now}}"
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("BOT_TOKEN", "env-token")
+	t.Setenv("TRAIN_API_KEY", "env-key")
+	t.Setenv("LOG_LEVEL", "debug")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{name: "Secret from env", got: cfg.Bot.Token, want: "env-token"},
+		{name: "File overrides default", got: cfg.RateLimit.UserPerMinute, want: 10},
+		{name: "Default kept", got: cfg.RateLimit.UserBurst, want: 5},
+		{name: "Duration from file", got: cfg.Train.Timeout, want: 5 * time.Second},
+		{name: "Env overrides default", got: cfg.Log.Level, want: "debug"},
+		{name: "Jobs from file", got: len(cfg.Notifications.Jobs), want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsSecretsInFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("train:\n  api_key: committed-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("BOT_TOKEN", "env-token")
+	t.Setenv("TRAIN_API_KEY", "env-key")
+
+	if _, err := Load(); err == nil {
+		t.Error("Load() should reject secrets in the config file")
+	}
+}
+
+func TestLoadAggregatesErrors(t *testing.T) {
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+	if _, err := Load(); err == nil {
+		t.Error("Load() with a missing config file should fail")
+	}
+
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("BOT_TOKEN", "")
+	t.Setenv("TRAIN_API_KEY", "")
+	t.Setenv("BOT_TIMEOUT", "sixty")
+	t.Setenv("LOG_FORMAT", "xml")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load() error = nil, want validation errors")
+	}
+	for _, want := range []string{"BOT_TIMEOUT", "BOT_TOKEN", "TRAIN_API_KEY", "xml"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Load() error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestLoadRejectsInvalidSchedule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	jobs := "notifications:\n  jobs:\n    - id: reminder\n      schedule: daily 25:00\n      template: hello\n      chat_ids: [1]\n"
+	if err := os.WriteFile(path, []byte(jobs), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("BOT_TOKEN", "env-token")
+	t.Setenv("TRAIN_API_KEY", "env-key")
+
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "reminder") {
+		t.Errorf("Load() error = %v, want the invalid schedule of job reminder", err)
+	}
+}
+
+func TestLoadNullTemplates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("notifications:\n  templates:\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("BOT_TOKEN", "env-token")
+	t.Setenv("TRAIN_API_KEY", "env-key")
+	t.Setenv("MONTHLY_TEMPLATE", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Notifications.Templates == nil {
+		t.Error("Templates = nil, want an empty map")
+	}
+}
+
+func TestLoadRejectsReservedJobID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	jobs := "notifications:\n  jobs:\n    - id: alerts_push\n      schedule: daily 08:00\n      template: hello\n      chat_ids: [1]\n"
+	if err := os.WriteFile(path, []byte(jobs), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("BOT_TOKEN", "env-token")
+	t.Setenv("TRAIN_API_KEY", "env-key")
+
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("Load() error = %v, want the reserved id of job alerts_push", err)
+	}
+}
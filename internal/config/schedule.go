@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Schedule is a parsed notification job schedule
+type Schedule struct {
+	// Kind is "monthly", "daily", "weekly" or "every"
+	Kind string
+	// Clock holds the time of day of daily and weekly schedules
+	Clock time.Time
+	// Weekday is the day of weekly schedules
+	Weekday time.Weekday
+	// Interval is the time between the runs of "every" schedules
+	Interval time.Duration
+}
+
+// ParseSchedule parses a job schedule. Supported forms: "monthly", "daily HH:MM",
+// "weekly <sun..sat> HH:MM" and "every <duration>".
+func ParseSchedule(spec string) (Schedule, error) {
+	fields := strings.Fields(strings.ToLower(spec))
+	if len(fields) == 0 {
+		return Schedule{}, fmt.Errorf("empty schedule")
+	}
+
+	schedule := Schedule{Kind: fields[0]}
+	switch fields[0] {
+	case "monthly":
+		if len(fields) != 1 {
+			return Schedule{}, fmt.Errorf("invalid monthly schedule %q", spec)
+		}
+	case "daily":
+		if len(fields) != 2 {
+			return Schedule{}, fmt.Errorf("invalid daily schedule %q", spec)
+		}
+		clock, err := time.Parse("15:04", fields[1])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("invalid time in schedule %q: %w", spec, err)
+		}
+		schedule.Clock = clock
+	case "weekly":
+		if len(fields) != 3 {
+			return Schedule{}, fmt.Errorf("invalid weekly schedule %q", spec)
+		}
+		weekday, ok := weekdays[fields[1]]
+		if !ok {
+			return Schedule{}, fmt.Errorf("invalid weekday in schedule %q", spec)
+		}
+		clock, err := time.Parse("15:04", fields[2])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("invalid time in schedule %q: %w", spec, err)
+		}
+		schedule.Weekday = weekday
+		schedule.Clock = clock
+	case "every":
+		if len(fields) != 2 {
+			return Schedule{}, fmt.Errorf("invalid interval schedule %q", spec)
+		}
+		interval, err := time.ParseDuration(fields[1])
+		if err != nil || interval <= 0 {
+			return Schedule{}, fmt.Errorf("invalid interval in schedule %q", spec)
+		}
+		schedule.Interval = interval
+	default:
+		return Schedule{}, fmt.Errorf("unknown schedule %q", spec)
+	}
+	return schedule, nil
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"rail-go/internal/secrets"
+)
+
+var (
+	validLogLevels    = map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
+	validLogEncodings = map[string]bool{"json": true, "console": true}
+	validLogOutputs   = map[string]bool{"stdout": true, "stderr": true, "file": true}
+	validExporters    = map[string]bool{"": true, "stdout": true, "otlp": true}
+)
+
+// Validate checks the whole configuration and reports every problem at once
+func (c *Config) Validate() error {
+	return c.validate(true)
+}
+
+func (c *Config) validate(requireBot bool) error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	// Bot configuration
+	check(!requireBot || c.Bot.Token != "", "bot token is required (%s)", secretSources("BOT_TOKEN"))
+	check(c.Bot.Timeout > 0, "bot timeout must be positive, got %d", c.Bot.Timeout)
+	check(c.Bot.ShutdownTimeout > 0, "bot shutdown timeout must be positive, got %s", c.Bot.ShutdownTimeout)
+
+	// Log configuration
+	check(validLogLevels[c.Log.Level], "invalid log level %q", c.Log.Level)
+	check(validLogEncodings[c.Log.Encoding], "invalid log format %q", c.Log.Encoding)
+	check(len(c.Log.Outputs) > 0, "at least one log output is required")
+	for _, output := range c.Log.Outputs {
+		check(validLogOutputs[output], "invalid log output %q", output)
+		check(output != "file" || c.Log.File != "", "log output \"file\" requires a log file (LOG_FILE)")
+	}
+	check(!c.Log.Privacy || c.Log.HashSalt != "", "log privacy mode requires a hash salt (%s)", secretSources("LOG_HASH_SALT"))
+	check(c.Log.MaxTextLength >= 0, "log max text length must not be negative")
+
+	// REST API configuration
+	check(len(c.API.Keys) == 0 || c.HTTP.Addr != "", "the REST API (API_KEYS) requires an HTTP address (HTTP_ADDR)")
+
+	// Tracing configuration
+	check(validExporters[c.Tracing.Exporter], "invalid tracing exporter %q", c.Tracing.Exporter)
+
+	// Rate limit configuration
+	check(c.RateLimit.UserPerMinute >= 0 && c.RateLimit.UserBurst >= 0, "user rate limit must not be negative")
+	check(c.RateLimit.GlobalPerMinute >= 0 && c.RateLimit.GlobalBurst >= 0, "global rate limit must not be negative")
+	check(c.RateLimit.UserPerMinute == 0 || c.RateLimit.UserBurst > 0, "user rate limit burst must be positive")
+	check(c.RateLimit.GlobalPerMinute == 0 || c.RateLimit.GlobalBurst > 0, "global rate limit burst must be positive")
+	check(c.RateLimit.InlinePerMinute >= 0 && c.RateLimit.InlineBurst >= 0, "inline rate limit must not be negative")
+	check(c.RateLimit.InlinePerMinute == 0 || c.RateLimit.InlineBurst > 0, "inline rate limit burst must be positive")
+	check(c.RateLimit.SendPerSecond >= 0 && c.RateLimit.ChatSendPerMinute >= 0 && c.RateLimit.GroupSendPerMinute >= 0,
+		"send rate limits must not be negative")
+
+	// Notifications configuration
+	check(c.Notifications.TimeFormat != "", "notification time format is required")
+	check(c.Notifications.AlertsInterval > 0, "alerts interval must be positive")
+	jobIDs := make(map[string]bool)
+	for i, job := range c.Notifications.Jobs {
+		check(job.ID != "", "notification job %d: id is required", i)
+		check(!jobIDs[job.ID], "notification job %q: duplicate id", job.ID)
+		check(job.ID != AlertsTaskID, "notification job %q: id is reserved for the alerts task", job.ID)
+		jobIDs[job.ID] = true
+		if job.Schedule == "" {
+			check(false, "notification job %q: schedule is required", job.ID)
+		} else if _, err := ParseSchedule(job.Schedule); err != nil {
+			check(false, "notification job %q: %v", job.ID, err)
+		}
+		check(job.Template != "", "notification job %q: template is required", job.ID)
+		check(len(job.ChatIDs) > 0, "notification job %q: at least one chat id is required", job.ID)
+		for _, chatID := range job.ChatIDs {
+			check(chatID != 0, "notification job %q: chat id must not be 0", job.ID)
+		}
+	}
+
+	// Train service configuration
+	check(c.Train.APIKey != "", "train API key is required (%s)", secretSources("TRAIN_API_KEY"))
+	check(c.Train.Timeout > 0, "train timeout must be positive")
+	check(isURL(c.Train.BaseURL), "invalid train base URL %q", c.Train.BaseURL)
+	check(isURL(c.Train.UpdatesURL), "invalid train updates URL %q", c.Train.UpdatesURL)
+
+	return errors.Join(errs...)
+}
+
+func isURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// secretSources lists where a missing secret can be provided
+func secretSources(name string) string {
+	return fmt.Sprintf("%s, %s_FILE or %s/%s", name, name, secrets.DefaultDir, strings.ToLower(name))
+}
@@ -0,0 +1,64 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"rail-go/internal/logger"
+)
+
+// Manager runs shutdown hooks in registration order within a shared deadline
+type Manager struct {
+	mu      sync.Mutex
+	logger  *logger.Logger
+	timeout time.Duration
+	hooks   []hook
+}
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func New(log *logger.Logger, timeout time.Duration) *Manager {
+	return &Manager{
+		logger:  log,
+		timeout: timeout,
+	}
+}
+
+// OnShutdown registers a hook. Hooks run in the order they are registered, so
+// stopping intake must be registered before waiting for in-flight work.
+func (m *Manager) OnShutdown(name string, fn func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook{name: name, fn: fn})
+}
+
+// Shutdown runs every hook, even after the deadline has passed, so that
+// quick steps like persisting state still happen. All hook errors are returned.
+func (m *Manager) Shutdown() error {
+	m.mu.Lock()
+	hooks := append([]hook(nil), m.hooks...)
+	m.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	m.logger.Info("shutting down", "timeout", m.timeout.String())
+
+	var errs []error
+	for _, h := range hooks {
+		start := time.Now()
+		if err := h.fn(ctx); err != nil {
+			m.logger.Error("shutdown step failed", "step", h.name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+			continue
+		}
+		m.logger.Info("shutdown step done", "step", h.name, "duration", time.Since(start).String())
+	}
+	return errors.Join(errs...)
+}
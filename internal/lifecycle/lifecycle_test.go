@@ -0,0 +1,38 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"rail-go/internal/logger"
+)
+
+func TestShutdown(t *testing.T) {
+	m := New(logger.New(), 20*time.Millisecond)
+
+	var steps []string
+	m.OnShutdown("intake", func(ctx context.Context) error {
+		steps = append(steps, "intake")
+		return nil
+	})
+	m.OnShutdown("drain", func(ctx context.Context) error {
+		steps = append(steps, "drain")
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	m.OnShutdown("state", func(ctx context.Context) error {
+		steps = append(steps, "state")
+		return nil
+	})
+
+	err := m.Shutdown()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want deadline exceeded", err)
+	}
+	if want := []string{"intake", "drain", "state"}; !reflect.DeepEqual(steps, want) {
+		t.Errorf("Shutdown() ran %v, want %v", steps, want)
+	}
+}
@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+)
+
+type contextKey struct{}
+
+// defaultLogger is used for contexts without a logger, e.g. those of scheduled
+// tasks and the CLI
+var defaultLogger atomic.Pointer[Logger]
+
+// initialLogger is the default until SetDefault is called
+var initialLogger = sync.OnceValue(New)
+
+// SetDefault sets the logger FromContext returns for contexts without one
+func SetDefault(l *Logger) {
+	defaultLogger.Store(l)
+}
+
+// NewContext returns a context carrying the logger
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by the context, or the default logger
+// when it has none
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	if l := defaultLogger.Load(); l != nil {
+		return l
+	}
+	return initialLogger()
+}
+
+// NewCorrelationID returns a random ID used to tie together the logs of one request
+func NewCorrelationID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
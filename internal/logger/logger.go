@@ -7,6 +7,8 @@ import (
 
 type Logger struct {
 	*zap.Logger
+	level  zap.AtomicLevel
+	redact *redactor
 }
 
 // New creates a new logger instance with production configuration
@@ -26,7 +28,7 @@ func New() *Logger {
 		panic("failed to create logger: " + err.Error())
 	}
 
-	return &Logger{Logger: logger}
+	return &Logger{Logger: logger, level: config.Level}
 }
 
 // NewDevelopment creates a new logger instance with development configuration
@@ -43,35 +45,50 @@ func NewDevelopment() *Logger {
 		panic("failed to create logger: " + err.Error())
 	}
 
-	return &Logger{Logger: logger}
+	return &Logger{Logger: logger, level: config.Level}
 }
 
 // Info logs an info message with structured fields
 func (l *Logger) Info(msg string, fields ...interface{}) {
-	zapFields := convertToZapFields(fields...)
+	zapFields := l.convertToZapFields(fields...)
 	l.Logger.Info(msg, zapFields...)
 }
 
+// Warn logs a warning message with structured fields
+func (l *Logger) Warn(msg string, fields ...interface{}) {
+	zapFields := l.convertToZapFields(fields...)
+	l.Logger.Warn(msg, zapFields...)
+}
+
 // Error logs an error message with structured fields
 func (l *Logger) Error(msg string, fields ...interface{}) {
-	zapFields := convertToZapFields(fields...)
+	zapFields := l.convertToZapFields(fields...)
 	l.Logger.Error(msg, zapFields...)
 }
 
 // Debug logs a debug message with structured fields
 func (l *Logger) Debug(msg string, fields ...interface{}) {
-	zapFields := convertToZapFields(fields...)
+	zapFields := l.convertToZapFields(fields...)
 	l.Logger.Debug(msg, zapFields...)
 }
 
 // Fatal logs a fatal message with structured fields and exits
 func (l *Logger) Fatal(msg string, fields ...interface{}) {
-	zapFields := convertToZapFields(fields...)
+	zapFields := l.convertToZapFields(fields...)
 	l.Logger.Fatal(msg, zapFields...)
 }
 
-// convertToZapFields converts variadic interface{} to zap.Field slice
-func convertToZapFields(fields ...interface{}) []zapcore.Field {
+// With returns a logger that adds the given key/value fields to every entry
+func (l *Logger) With(fields ...interface{}) *Logger {
+	return &Logger{
+		Logger: l.Logger.With(l.convertToZapFields(fields...)...),
+		level:  l.level,
+		redact: l.redact,
+	}
+}
+
+// convertToZapFields converts variadic interface{} to zap.Field slice, redacting personal data
+func (l *Logger) convertToZapFields(fields ...interface{}) []zapcore.Field {
 	if len(fields) == 0 {
 		return nil
 	}
@@ -85,7 +102,14 @@ func convertToZapFields(fields ...interface{}) []zapcore.Field {
 		if !ok {
 			continue
 		}
-		zapFields = append(zapFields, zap.Any(key, fields[i+1]))
+		value := fields[i+1]
+		if l.redact != nil {
+			var keep bool
+			if value, keep = l.redact.field(key, value); !keep {
+				continue
+			}
+		}
+		zapFields = append(zapFields, zap.Any(key, value))
 	}
 	return zapFields
 }
@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rail-go/internal/config"
+)
+
+func TestNewFromConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		level    string
+		encoding string
+		outputs  []string
+		wantErr  bool
+	}{
+		{name: "JSON to file", level: "info", encoding: "json", outputs: []string{"file"}},
+		{name: "Console to file", level: "debug", encoding: "console", outputs: []string{"file"}},
+		{name: "Invalid level", level: "loud", encoding: "json", outputs: []string{"stdout"}, wantErr: true},
+		{name: "Invalid encoding", level: "info", encoding: "xml", outputs: []string{"stdout"}, wantErr: true},
+		{name: "Invalid output", level: "info", encoding: "json", outputs: []string{"syslog"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Log.Level = tt.level
+			cfg.Log.Encoding = tt.encoding
+			cfg.Log.Outputs = tt.outputs
+			cfg.Log.File = filepath.Join(t.TempDir(), "bot.log")
+
+			log, err := NewFromConfig(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewFromConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			log.Info("first")
+			if err := log.With("request", 1).SetLevel("error"); err != nil {
+				t.Fatalf("SetLevel() error = %v", err)
+			}
+			log.Info("second")
+			log.Sync()
+
+			data, err := os.ReadFile(cfg.Log.File)
+			if err != nil {
+				t.Fatalf("failed to read log file: %v", err)
+			}
+			if !strings.Contains(string(data), "first") || strings.Contains(string(data), "second") {
+				t.Errorf("log file got %q, want only the entry logged before the level change", data)
+			}
+			if log.Level() != "error" {
+				t.Errorf("Level() got %s, want error", log.Level())
+			}
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) == nil {
+		t.Fatal("FromContext() without a logger = nil")
+	}
+
+	def := New()
+	SetDefault(def)
+	t.Cleanup(func() { SetDefault(nil) })
+	if got := FromContext(context.Background()); got != def {
+		t.Error("FromContext() without a logger does not return the default logger")
+	}
+
+	carried := New()
+	if got := FromContext(NewContext(context.Background(), carried)); got != carried {
+		t.Error("FromContext() does not return the logger of the context")
+	}
+}
@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// idFields hold Telegram user and chat IDs
+var idFields = map[string]bool{
+	"user_id":        true,
+	"chat_id":        true,
+	"target_chat_id": true,
+}
+
+// textFields hold free text typed or sent by users
+var textFields = map[string]bool{
+	"text":          true,
+	"input":         true,
+	"callback_data": true,
+	"data":          true,
+	"query":         true,
+	"payload":       true,
+}
+
+// travelFields hold the stations and routes users travel between. Station IDs are
+// few enough to guess from a hash, so these are always dropped.
+var travelFields = map[string]bool{
+	"from":           true,
+	"to":             true,
+	"from_station":   true,
+	"to_station":     true,
+	"station_id":     true,
+	"selected_route": true,
+}
+
+// redactor minimizes the personal data written to logs: IDs are replaced by a salted hash,
+// user text is truncated (or dropped) and travel routes are dropped. This applies at every
+// level, debug included: the level can be raised at runtime by an admin command or the
+// /loglevel endpoint, and data minimization that a command can turn off would not hold.
+// Without privacy mode, message contents are only logged at debug level.
+type redactor struct {
+	salt          []byte
+	maxTextLength int
+}
+
+func newRedactor(salt string, maxTextLength int) *redactor {
+	return &redactor{salt: []byte(salt), maxTextLength: maxTextLength}
+}
+
+// field returns the value to log for the field, and false when the field must be dropped
+func (r *redactor) field(key string, value interface{}) (interface{}, bool) {
+	switch {
+	case idFields[key]:
+		return r.hash(fmt.Sprint(value)), true
+	case travelFields[key]:
+		return nil, false
+	case textFields[key]:
+		text := fmt.Sprint(value)
+		if r.maxTextLength <= 0 {
+			return nil, false
+		}
+		if runes := []rune(text); len(runes) > r.maxTextLength {
+			return string(runes[:r.maxTextLength]) + "…", true
+		}
+		return text, true
+	default:
+		return value, true
+	}
+}
+
+// hash returns a short keyed hash, stable for the same salt so one user's logs can still be correlated
+func (r *redactor) hash(value string) string {
+	mac := hmac.New(sha256.New, r.salt)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
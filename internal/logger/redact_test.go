@@ -0,0 +1,43 @@
+package logger
+
+import "testing"
+
+func TestRedactorField(t *testing.T) {
+	r := newRedactor("salt", 5)
+	dropText := newRedactor("salt", 0)
+
+	tests := []struct {
+		name     string
+		redactor *redactor
+		key      string
+		value    interface{}
+		want     interface{}
+		keep     bool
+	}{
+		{name: "Hashed user ID", redactor: r, key: "user_id", value: int64(42), want: r.hash("42"), keep: true},
+		{name: "Hashed chat ID", redactor: r, key: "chat_id", value: int64(42), want: r.hash("42"), keep: true},
+		{name: "Truncated text", redactor: r, key: "text", value: "הרצליה לתל אביב", want: "הרצלי…", keep: true},
+		{name: "Short text", redactor: r, key: "text", value: "🚆", want: "🚆", keep: true},
+		{name: "Dropped text", redactor: dropText, key: "text", value: "hello", keep: false},
+		{name: "Dropped station", redactor: r, key: "station_id", value: "3500", keep: false},
+		{name: "Dropped route", redactor: r, key: "from", value: "3500", keep: false},
+		{name: "Truncated callback payload", redactor: r, key: "payload", value: "3500-4600", want: "3500-…", keep: true},
+		{name: "Other field", redactor: r, key: "state", value: "default", want: "default", keep: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, keep := tt.redactor.field(tt.key, tt.value)
+			if keep != tt.keep {
+				t.Fatalf("field() keep = %v, want %v", keep, tt.keep)
+			}
+			if keep && got != tt.want {
+				t.Errorf("field() got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if r.hash("42") == newRedactor("other", 5).hash("42") {
+		t.Error("hash() should depend on the salt")
+	}
+}
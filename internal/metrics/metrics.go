@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// UpdatesHandled counts Telegram updates by type (message, callback_query, ...)
+	UpdatesHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "railgo_updates_handled_total",
+		Help: "Telegram updates handled, by update type and outcome.",
+	}, []string{"type", "outcome"})
+
+	// HandlerDuration measures how long handling an update takes
+	HandlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "railgo_handler_duration_seconds",
+		Help:    "Time spent handling a Telegram update, by update type.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"type"})
+
+	// UpdatesRejected counts updates dropped by access control or rate limiting
+	UpdatesRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "railgo_updates_rejected_total",
+		Help: "Telegram updates rejected before handling, by reason.",
+	}, []string{"reason"})
+
+	// SendQueueWait measures how long outgoing Telegram requests wait for the rate limits
+	SendQueueWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "railgo_send_queue_wait_seconds",
+		Help:    "Time outgoing Telegram requests waited in the send queue, by priority.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"priority"})
+
+	// TelegramThrottled counts requests Telegram rejected with a retry_after
+	TelegramThrottled = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "railgo_telegram_throttled_total",
+		Help: "Telegram requests rejected with 429 Too Many Requests.",
+	})
+
+	// RailAPIDuration measures Israel Railways API calls
+	RailAPIDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "railgo_rail_api_duration_seconds",
+		Help:    "Israel Railways API request latency, by endpoint.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	// RailAPIRequests counts Israel Railways API calls by status code ("error" when no response)
+	RailAPIRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "railgo_rail_api_requests_total",
+		Help: "Israel Railways API requests, by endpoint and status code.",
+	}, []string{"endpoint", "code"})
+
+	// CacheRequests counts schedule cache lookups; the hit ratio is hit / (hit + miss)
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "railgo_cache_requests_total",
+		Help: "Schedule cache lookups, by result (hit or miss).",
+	}, []string{"result"})
+
+	// SchedulerRuns counts scheduled task runs by outcome
+	SchedulerRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "railgo_scheduler_runs_total",
+		Help: "Scheduled task runs, by task and outcome (success or failure).",
+	}, []string{"task", "outcome"})
+
+	// APIRequests counts REST API requests by route and status code
+	APIRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "railgo_api_requests_total",
+		Help: "REST API requests, by route and status code.",
+	}, []string{"route", "code"})
+
+	// APIDuration measures REST API request latency
+	APIDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "railgo_api_duration_seconds",
+		Help:    "REST API request latency, by route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route"})
+)
+
+// Outcome returns the outcome label for an error
+func Outcome(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// idleBucketTTL is how long an unused per-client bucket is kept before it is dropped
+const idleBucketTTL = 10 * time.Minute
+
+// tokenBucket refills at a fixed rate up to burst tokens; each request takes one token
+type tokenBucket struct {
+	tokens   float64
+	last     time.Time
+	perSec   float64
+	burst    float64
+	lastSeen time.Time
+}
+
+func newTokenBucket(perMinute, burst int, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		tokens:   float64(burst),
+		last:     now,
+		perSec:   float64(perMinute) / 60,
+		burst:    float64(burst),
+		lastSeen: now,
+	}
+}
+
+// ready refills the bucket and reports whether it holds a token, without taking it
+func (b *tokenBucket) ready(now time.Time) bool {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.perSec
+	}
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.lastSeen = now
+
+	return b.tokens >= 1
+}
+
+// Limiter applies a token bucket per client (a Telegram user, an API key) and one
+// shared by all clients. A limit of zero requests per minute disables that bucket.
+type Limiter struct {
+	mu              sync.Mutex
+	userPerMinute   int
+	userBurst       int
+	globalPerMinute int
+	globalBurst     int
+	global          *tokenBucket
+	clients         map[string]*tokenBucket
+	lastCleanup     time.Time
+	now             func() time.Time
+}
+
+func New(userPerMinute, userBurst, globalPerMinute, globalBurst int) *Limiter {
+	l := &Limiter{
+		clients: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+	l.SetLimits(userPerMinute, userBurst, globalPerMinute, globalBurst)
+	return l
+}
+
+// SetLimits changes the limits, resetting all buckets
+func (l *Limiter) SetLimits(userPerMinute, userBurst, globalPerMinute, globalBurst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.userPerMinute, l.userBurst = userPerMinute, userBurst
+	l.globalPerMinute, l.globalBurst = globalPerMinute, globalBurst
+	l.global = newTokenBucket(globalPerMinute, globalBurst, now)
+	l.clients = make(map[string]*tokenBucket)
+	l.lastCleanup = now
+}
+
+// Allow reports whether a request from the client is within both limits. A
+// token is taken from the buckets only when both allow the request, so requests
+// turned away by the global limit do not use up the client's budget.
+func (l *Limiter) Allow(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.cleanup(now)
+
+	var buckets []*tokenBucket
+	if l.userPerMinute > 0 {
+		bucket, ok := l.clients[client]
+		if !ok {
+			bucket = newTokenBucket(l.userPerMinute, l.userBurst, now)
+			l.clients[client] = bucket
+		}
+		buckets = append(buckets, bucket)
+	}
+	if l.globalPerMinute > 0 {
+		buckets = append(buckets, l.global)
+	}
+
+	for _, bucket := range buckets {
+		if !bucket.ready(now) {
+			return false
+		}
+	}
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+	return true
+}
+
+// cleanup drops buckets of clients that have been idle for a while
+func (l *Limiter) cleanup(now time.Time) {
+	if now.Sub(l.lastCleanup) < idleBucketTTL {
+		return
+	}
+	for client, bucket := range l.clients {
+		if now.Sub(bucket.lastSeen) > idleBucketTTL {
+			delete(l.clients, client)
+		}
+	}
+	l.lastCleanup = now
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+type rateStep struct {
+	after  time.Duration
+	client string
+	want   bool
+}
+
+func TestRateLimiter(t *testing.T) {
+	tests := []struct {
+		name                         string
+		userPerMinute, userBurst     int
+		globalPerMinute, globalBurst int
+		steps                        []rateStep
+	}{
+		{
+			name:          "User burst then refill",
+			userPerMinute: 60, userBurst: 2,
+			steps: []rateStep{
+				{0, "1", true},
+				{0, "1", true},
+				{0, "1", false},
+				{0, "2", true},
+				{time.Second, "1", true},
+				{0, "1", false},
+			},
+		},
+		{
+			name:            "Global limit shared by users",
+			globalPerMinute: 60, globalBurst: 2,
+			steps: []rateStep{
+				{0, "1", true},
+				{0, "2", true},
+				{0, "3", false},
+				{time.Second, "3", true},
+			},
+		},
+		{
+			name:          "Global limit does not use up the user burst",
+			userPerMinute: 60, userBurst: 2,
+			globalPerMinute: 120, globalBurst: 1,
+			steps: []rateStep{
+				{0, "1", true},
+				{0, "2", false},
+				{0, "2", false},
+				{500 * time.Millisecond, "2", true},
+				{500 * time.Millisecond, "2", true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2025, time.March, 5, 10, 0, 0, 0, time.UTC)
+			limiter := New(tt.userPerMinute, tt.userBurst, tt.globalPerMinute, tt.globalBurst)
+			limiter.now = func() time.Time { return now }
+
+			for i, step := range tt.steps {
+				now = now.Add(step.after)
+				if got := limiter.Allow(step.client); got != step.want {
+					t.Errorf("step %d: Allow(%q) got %v, want %v", i, step.client, got, step.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"rail-go/internal/bot"
+	"rail-go/internal/config"
+	"rail-go/internal/train"
+)
+
+// jobData is the data available to notification templates
+type jobData struct {
+	Now    time.Time
+	JobID  string
+	ChatID int64
+}
+
+// ScheduleNotificationJobs registers all notification jobs from config, replacing the
+// jobs registered by a previous call. Nothing is changed if any job is invalid.
+func (s *Scheduler) ScheduleNotificationJobs(ctx context.Context, botService *bot.Service, cfg *config.Config) error {
+	tasks, err := s.NotificationTasks(botService, cfg)
+	if err != nil {
+		return err
+	}
+	return s.ReplaceNotificationJobs(ctx, tasks)
+}
+
+// NotificationTasks builds the tasks of the notification jobs from config without
+// scheduling them
+func (s *Scheduler) NotificationTasks(botService *bot.Service, cfg *config.Config) ([]*Task, error) {
+	tasks := make([]*Task, 0, len(cfg.Notifications.Jobs))
+	for _, job := range cfg.Notifications.Jobs {
+		task, err := s.notificationTask(botService, cfg, job)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// ReplaceNotificationJobs schedules tasks built by NotificationTasks in place of
+// the notification jobs scheduled before. A job still in the config stays paused
+// if it was, and keeps its last run and error. Nothing is changed if a task
+// would take the place of the alerts task.
+func (s *Scheduler) ReplaceNotificationJobs(ctx context.Context, tasks []*Task) error {
+	for _, task := range tasks {
+		if task.ID == config.AlertsTaskID {
+			return fmt.Errorf("job %s: the id is reserved for the alerts task", task.ID)
+		}
+	}
+
+	s.replaceMu.Lock()
+	defer s.replaceMu.Unlock()
+
+	s.mu.Lock()
+	previous := s.jobIDs
+	s.jobIDs = make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		s.jobIDs = append(s.jobIDs, task.ID)
+		if old, ok := s.tasks[task.ID]; ok && slices.Contains(previous, task.ID) {
+			task.carryOver(old)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, id := range previous {
+		s.RemoveTask(id)
+	}
+	for _, task := range tasks {
+		if err := s.ScheduleTask(ctx, task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notificationTask builds the task of a templated notification job from config
+func (s *Scheduler) notificationTask(botService *bot.Service, cfg *config.Config, job config.NotificationJob) (*Task, error) {
+	next, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("job %s: %w", job.ID, err)
+	}
+
+	trainService := botService.TrainService()
+	// The functions are bound to the context of each run by renderJob
+	tmpl, err := template.New(job.ID).
+		Funcs(templateFuncs(context.Background(), cfg, trainService)).
+		Parse(job.Template)
+	if err != nil {
+		return nil, fmt.Errorf("job %s: failed to parse template: %w", job.ID, err)
+	}
+
+	task := &Task{
+		ID:   job.ID,
+		Next: next,
+		// One chat failing does not keep the notification from the others
+		Execute: func(ctx context.Context) error {
+			var errs []error
+			for _, chatID := range job.ChatIDs {
+				data := jobData{Now: nowFunc().Local(), JobID: job.ID, ChatID: chatID}
+				text, err := renderJob(ctx, tmpl, cfg, trainService, data)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				if err := botService.SendMessage(ctx, chatID, text); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			return errors.Join(errs...)
+		},
+		Logger: s.logger,
+	}
+
+	return task, nil
+}
+
+// renderJob renders the template of a job with lookups made with the context of
+// the run, so they are traced with it and outlive a shutdown like the run does
+func renderJob(ctx context.Context, tmpl *template.Template, cfg *config.Config, trainService *train.Service, data jobData) (string, error) {
+	run, err := tmpl.Clone()
+	if err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	run.Funcs(templateFuncs(ctx, cfg, trainService))
+
+	var text strings.Builder
+	if err := run.Execute(&text, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return text.String(), nil
+}
+
+// templateFuncs returns the date helpers and live train data lookups available to templates
+func templateFuncs(ctx context.Context, cfg *config.Config, trainService *train.Service) template.FuncMap {
+	return template.FuncMap{
+		"formatTime": func(t time.Time) string {
+			return t.Format(cfg.Notifications.TimeFormat)
+		},
+		"date": func(layout string, t time.Time) string {
+			return t.Format(layout)
+		},
+		"addDays": func(days int, t time.Time) time.Time {
+			return t.AddDate(0, 0, days)
+		},
+		"daysLeftInMonth": func(t time.Time) int {
+			lastOfMonth := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location())
+			return lastOfMonth.Day() - t.Day()
+		},
+		"station": trainService.GetStationName,
+		"schedule": func(from, to string) (string, error) {
+			chunks, err := trainService.GetSchedule(ctx, from, to)
+			if err != nil {
+				return "", err
+			}
+			return strings.Join(chunks, ""), nil
+		},
+	}
+}
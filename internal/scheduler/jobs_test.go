@@ -0,0 +1,43 @@
+package scheduler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+
+	"rail-go/internal/logger"
+	"rail-go/internal/train"
+)
+
+func TestRenderJobUsesRunContext(t *testing.T) {
+	rail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result": {"travels": [
+			{"trains": [{"orignStation": 3500, "destinationStation": 4600, "departureTime": "2025-05-04T08:05:00", "arrivalTime": "2025-05-04T08:25:00", "originPlatform": 1, "destPlatform": 2}]}
+		]}}`))
+	}))
+	t.Cleanup(rail.Close)
+
+	cfg := train.NewTestConfig()
+	cfg.Train.BaseURL = rail.URL
+	trainService := train.NewService(cfg, logger.New())
+	tmpl := template.Must(template.New("job").
+		Funcs(templateFuncs(context.Background(), cfg, trainService)).
+		Parse(`{{schedule "3500" "4600"}}`))
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := renderJob(cancelled, tmpl, cfg, trainService, jobData{}); err == nil {
+		t.Error("renderJob() with a cancelled run context = nil error, want the lookup to fail")
+	}
+
+	text, err := renderJob(context.Background(), tmpl, cfg, trainService, jobData{})
+	if err != nil {
+		t.Fatalf("renderJob() error = %v", err)
+	}
+	if !strings.Contains(text, "08:05") {
+		t.Errorf("renderJob() = %q, want the 08:05 train", text)
+	}
+}
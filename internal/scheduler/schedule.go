@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"time"
+
+	"rail-go/internal/config"
+)
+
+// ParseSchedule parses a job schedule into a function returning the next run after a given time.
+// The forms are those of config.ParseSchedule.
+func ParseSchedule(spec string) (func(time.Time) time.Time, error) {
+	schedule, err := config.ParseSchedule(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	switch schedule.Kind {
+	case "monthly":
+		return nextMonthlyAfter, nil
+	case "daily":
+		return func(now time.Time) time.Time {
+			next := atClock(now, schedule.Clock)
+			if !next.After(now) {
+				next = next.AddDate(0, 0, 1)
+			}
+			return next
+		}, nil
+	case "weekly":
+		return func(now time.Time) time.Time {
+			next := atClock(now, schedule.Clock).AddDate(0, 0, int(schedule.Weekday-now.Weekday()+7)%7)
+			if !next.After(now) {
+				next = next.AddDate(0, 0, 7)
+			}
+			return next
+		}, nil
+	default:
+		return func(now time.Time) time.Time {
+			return now.Add(schedule.Interval)
+		}, nil
+	}
+}
+
+// nextMonthlyAfter returns the first monthly run strictly after now. The run is
+// five days before the end of the month at 08:00, once per month.
+func nextMonthlyAfter(now time.Time) time.Time {
+	next := monthlyTarget(now.Year(), now.Month(), now.Location())
+	if !next.After(now) {
+		next = monthlyTarget(now.Year(), now.Month()+1, now.Location())
+	}
+	return next
+}
+
+// monthlyTarget returns the monthly run of the given month
+func monthlyTarget(year int, month time.Month, loc *time.Location) time.Time {
+	lastOfMonth := time.Date(year, month+1, 0, 0, 0, 0, 0, loc)
+	return time.Date(lastOfMonth.Year(), lastOfMonth.Month(), lastOfMonth.Day()-5, 8, 0, 0, 0, loc)
+}
+
+func atClock(day time.Time, clock time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, day.Location())
+}
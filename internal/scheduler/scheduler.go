@@ -3,24 +3,43 @@ package scheduler
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"rail-go/internal/bot"
+	"rail-go/internal/config"
 	"rail-go/internal/logger"
+	"rail-go/internal/metrics"
 )
 
 type Task struct {
 	ID       string
 	Interval time.Duration
-	Execute  func() error
-	Logger   *logger.Logger
+	// Next returns the next run after the given time. When set, it takes precedence over Interval.
+	Next    func(time.Time) time.Time
+	Execute func(ctx context.Context) error
+	Logger  *logger.Logger
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	runNow  chan struct{}
+	paused  bool
+	lastRun time.Time
+	nextRun time.Time
+	lastErr error
 }
 
 type Scheduler struct {
 	tasks  map[string]*Task
+	jobIDs []string
 	mu     sync.RWMutex
-	logger *logger.Logger
+	// replaceMu keeps replacements of the notification jobs from interleaving
+	replaceMu sync.Mutex
+	logger    *logger.Logger
+	// running tracks the task loops so Stop can wait for in-flight runs
+	running sync.WaitGroup
+	stopped bool
 }
 
 func New(logger *logger.Logger) *Scheduler {
@@ -31,29 +50,40 @@ func New(logger *logger.Logger) *Scheduler {
 }
 
 func (s *Scheduler) ScheduleTask(ctx context.Context, task *Task) error {
+	taskCtx, cancel := context.WithCancel(ctx)
+	task.cancel = cancel
+	task.runNow = make(chan struct{}, 1)
+
 	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		cancel()
+		return fmt.Errorf("scheduler is stopped")
+	}
+	if existing, ok := s.tasks[task.ID]; ok {
+		existing.cancel()
+	}
 	s.tasks[task.ID] = task
+	s.running.Add(1)
 	s.mu.Unlock()
 
 	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				s.logger.Error("panic in scheduled task", "taskID", task.ID, "error", r)
-			}
-		}()
-
-		ticker := time.NewTicker(task.Interval)
-		defer ticker.Stop()
+		defer s.running.Done()
+		timer := time.NewTimer(time.Until(task.scheduleNext(nowFunc())))
+		defer timer.Stop()
 
 		for {
 			select {
-			case <-ctx.Done():
+			case <-taskCtx.Done():
 				s.logger.Info("shutting down scheduler", "taskID", task.ID)
 				return
-			case <-ticker.C:
-				if err := task.Execute(); err != nil {
-					s.logger.Error("task execution failed", "taskID", task.ID, "error", err)
+			case <-task.runNow:
+				s.run(taskCtx, task)
+			case <-timer.C:
+				if !task.isPaused() {
+					s.run(taskCtx, task)
 				}
+				timer.Reset(time.Until(task.scheduleNext(nowFunc())))
 			}
 		}
 	}()
@@ -61,12 +91,97 @@ func (s *Scheduler) ScheduleTask(ctx context.Context, task *Task) error {
 	return nil
 }
 
+// run executes the task once and records the outcome. A run that has started is
+// not interrupted when the task is removed or the scheduler stops.
+func (s *Scheduler) run(ctx context.Context, task *Task) {
+	ctx = context.WithoutCancel(ctx)
+	var err error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				s.logger.Error("panic in scheduled task", "taskID", task.ID, "error", r)
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		err = task.Execute(ctx)
+	}()
+
+	metrics.SchedulerRuns.WithLabelValues(task.ID, metrics.Outcome(err)).Inc()
+	if err != nil {
+		s.logger.Error("task execution failed", "taskID", task.ID, "error", err)
+	}
+
+	task.mu.Lock()
+	task.lastRun = nowFunc()
+	task.lastErr = err
+	task.mu.Unlock()
+}
+
+// scheduleNext computes and records the next run after now
+func (t *Task) scheduleNext(now time.Time) time.Time {
+	next := now.Add(t.Interval)
+	if t.Next != nil {
+		next = t.Next(now)
+	}
+
+	t.mu.Lock()
+	t.nextRun = next
+	t.mu.Unlock()
+	return next
+}
+
+func (t *Task) isPaused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.paused
+}
+
+// carryOver copies the pause and the run history of the task it replaces
+func (t *Task) carryOver(old *Task) {
+	old.mu.Lock()
+	defer old.mu.Unlock()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.paused = old.paused
+	t.lastRun = old.lastRun
+	t.lastErr = old.lastErr
+}
+
+// RemoveTask stops the task and removes it from the scheduler
 func (s *Scheduler) RemoveTask(taskID string) {
 	s.mu.Lock()
-	delete(s.tasks, taskID)
+	if task, ok := s.tasks[taskID]; ok {
+		task.cancel()
+		delete(s.tasks, taskID)
+	}
 	s.mu.Unlock()
 }
 
+// Stop cancels all tasks and waits for runs in progress to finish or for ctx to expire.
+// No tasks can be scheduled afterwards.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	for _, task := range s.tasks {
+		task.cancel()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("scheduled tasks not finished: %w", ctx.Err())
+	}
+}
+
 func (s *Scheduler) GetTask(taskID string) (*Task, bool) {
 	s.mu.RLock()
 	task, exists := s.tasks[taskID]
@@ -74,47 +189,81 @@ func (s *Scheduler) GetTask(taskID string) (*Task, bool) {
 	return task, exists
 }
 
-var nowFunc = time.Now
+// PauseTask skips the scheduled runs of the task until it is resumed
+func (s *Scheduler) PauseTask(taskID string) error {
+	return s.setPaused(taskID, true)
+}
+
+// ResumeTask resumes the scheduled runs of a paused task
+func (s *Scheduler) ResumeTask(taskID string) error {
+	return s.setPaused(taskID, false)
+}
+
+func (s *Scheduler) setPaused(taskID string, paused bool) error {
+	task, ok := s.GetTask(taskID)
+	if !ok {
+		return fmt.Errorf("task %s not found", taskID)
+	}
 
-func nextMonthlyRun() time.Time {
-	now := nowFunc()
+	task.mu.Lock()
+	task.paused = paused
+	task.mu.Unlock()
 
-	year, month, _ := now.Date()
-	loc := now.Location()
+	s.logger.Info("task state changed", "taskID", taskID, "paused", paused)
+	return nil
+}
 
-	// Calculate the last day of the current month.
-	firstOfMonth := time.Date(year, month, 1, 0, 0, 0, 0, loc)
-	lastOfMonth := firstOfMonth.AddDate(0, 1, -1)
+// RunTask triggers an immediate run of the task, even when it is paused
+func (s *Scheduler) RunTask(taskID string) error {
+	task, ok := s.GetTask(taskID)
+	if !ok {
+		return fmt.Errorf("task %s not found", taskID)
+	}
 
-	durationUntilEnd := lastOfMonth.Sub(now)
-	var scheduledTime time.Time
-	if durationUntilEnd.Hours() < 120.0 {
-		daysLeft := int(durationUntilEnd.Hours() / 24)
-		lastOfMonth = firstOfMonth.AddDate(0, 1, -daysLeft)
-		scheduledTime = time.Date(year, month, lastOfMonth.Day(), 8, 0, 0, 0, loc)
-	} else {
-		scheduledTime = time.Date(year, month, lastOfMonth.Day()-5, 8, 0, 0, 0, loc)
+	select {
+	case task.runNow <- struct{}{}:
+		return nil
+	default:
+		return fmt.Errorf("task %s already has a pending run", taskID)
 	}
+}
+
+// Tasks returns the status of all scheduled tasks, sorted by ID
+func (s *Scheduler) Tasks() []bot.TaskStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	return scheduledTime
+	statuses := make([]bot.TaskStatus, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		task.mu.Lock()
+		status := bot.TaskStatus{
+			ID:      task.ID,
+			Paused:  task.paused,
+			LastRun: task.lastRun,
+			NextRun: task.nextRun,
+		}
+		if task.lastErr != nil {
+			status.LastError = task.lastErr.Error()
+		}
+		task.mu.Unlock()
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].ID < statuses[j].ID
+	})
+	return statuses
 }
 
-func (s *Scheduler) ScheduleMonthlyTask(ctx context.Context, botService *bot.Service, chatID int64) error {
+var nowFunc = time.Now
+
+func (s *Scheduler) ScheduleAlertsTask(ctx context.Context, botService *bot.Service, interval time.Duration) error {
 	task := &Task{
-		ID:       "monthly_notification",
-		Interval: s.calculateMonthlyInterval(),
-		Execute: func() error {
-			t := time.Now()
-			return botService.SendMessage(ctx, chatID,
-				fmt.Sprintf("%s need to use sibus", t.Local().Format("Mon Jan 2 15:04:05")))
-		},
-		Logger: s.logger,
+		ID:       config.AlertsTaskID,
+		Interval: interval,
+		Execute:  botService.NotifyAlerts,
+		Logger:   s.logger,
 	}
 
 	return s.ScheduleTask(ctx, task)
 }
-
-func (s *Scheduler) calculateMonthlyInterval() time.Duration {
-	nextRun := nextMonthlyRun()
-	return time.Until(nextRun)
-}
@@ -1,34 +1,306 @@
 package scheduler
 
 import (
-	"reflect"
+	"context"
+	"errors"
 	"testing"
 	"time"
+
+	"rail-go/internal/bot"
+	"rail-go/internal/config"
+	"rail-go/internal/logger"
 )
 
-func Test_nextMonthlyRun(t *testing.T) {
+func TestNextMonthlyAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "Start of the month",
+			now:  time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2025, time.January, 26, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Just before this month's run",
+			now:  time.Date(2025, time.March, 26, 7, 59, 0, 0, time.UTC),
+			want: time.Date(2025, time.March, 26, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "At this month's run",
+			now:  time.Date(2025, time.March, 26, 8, 0, 0, 0, time.UTC),
+			want: time.Date(2025, time.April, 25, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Past this month's run",
+			now:  time.Date(2025, time.January, 27, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2025, time.February, 23, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Leap year February",
+			now:  time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2024, time.February, 24, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Last day of the month",
+			now:  time.Date(2025, time.April, 30, 23, 59, 0, 0, time.UTC),
+			want: time.Date(2025, time.May, 26, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "End of the year",
+			now:  time.Date(2025, time.December, 31, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2026, time.January, 26, 8, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextMonthlyAfter(tt.now); !got.Equal(tt.want) {
+				t.Errorf("nextMonthlyAfter(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	now := time.Date(2025, time.March, 5, 10, 0, 0, 0, time.UTC) // Wednesday
+
 	tests := []struct {
 		name    string
-		nowFunc func() time.Time
+		spec    string
+		now     time.Time
 		want    time.Time
+		wantErr bool
 	}{
 		{
-			name:    "next monthly run",
-			nowFunc: func() time.Time { return time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC) },
-			want:    time.Date(1, time.January, 26, 8, 0, 0, 0, time.UTC),
+			name: "monthly",
+			spec: "monthly",
+			want: time.Date(2025, time.March, 26, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "monthly after this month's run",
+			spec: "monthly",
+			now:  time.Date(2025, time.March, 31, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2025, time.April, 25, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "daily later today",
+			spec: "daily 18:30",
+			want: time.Date(2025, time.March, 5, 18, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "daily tomorrow",
+			spec: "daily 07:00",
+			want: time.Date(2025, time.March, 6, 7, 0, 0, 0, time.UTC),
 		},
 		{
-			name:    "next monthly run",
-			nowFunc: func() time.Time { return time.Date(2025, time.January, 27, 0, 0, 0, 0, time.UTC) },
-			want:    time.Date(2025, time.January, 28, 8, 0, 0, 0, time.UTC),
+			name: "weekly",
+			spec: "weekly sun 08:00",
+			want: time.Date(2025, time.March, 9, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "weekly same day earlier",
+			spec: "weekly wed 09:00",
+			want: time.Date(2025, time.March, 12, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "every",
+			spec: "every 1h",
+			want: time.Date(2025, time.March, 5, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "invalid",
+			spec:    "hourly",
+			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			nowFunc = tt.nowFunc
-			if got := nextMonthlyRun(); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("nextMonthlyRun() = %v, want %v", got, tt.want)
+			next, err := ParseSchedule(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSchedule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			from := now
+			if !tt.now.IsZero() {
+				from = tt.now
+			}
+			if got := next(from); !got.Equal(tt.want) {
+				t.Errorf("next() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestMonthlyScheduleRepeats(t *testing.T) {
+	next, err := ParseSchedule("monthly")
+	if err != nil {
+		t.Fatalf("ParseSchedule() error = %v", err)
+	}
+
+	// Each run is fed back in, as the scheduler does
+	want := []time.Time{
+		time.Date(2025, time.January, 26, 8, 0, 0, 0, time.UTC),
+		time.Date(2025, time.February, 23, 8, 0, 0, 0, time.UTC),
+		time.Date(2025, time.March, 26, 8, 0, 0, 0, time.UTC),
+		time.Date(2025, time.April, 25, 8, 0, 0, 0, time.UTC),
+		time.Date(2025, time.December, 26, 8, 0, 0, 0, time.UTC),
+		time.Date(2026, time.January, 26, 8, 0, 0, 0, time.UTC),
+	}
+	run := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i, w := range want {
+		if i == 4 {
+			run = time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC)
+		}
+		run = next(run)
+		if !run.Equal(w) {
+			t.Fatalf("run %d = %v, want %v", i, run, w)
+		}
+	}
+}
+
+func TestSchedulerTaskControl(t *testing.T) {
+	s := New(logger.New())
+	ran := make(chan struct{}, 1)
+	task := &Task{
+		ID:       "test",
+		Interval: time.Hour,
+		Execute: func(ctx context.Context) error {
+			ran <- struct{}{}
+			return errors.New("boom")
+		},
+	}
+	if err := s.ScheduleTask(context.Background(), task); err != nil {
+		t.Fatalf("ScheduleTask() error = %v", err)
+	}
+
+	if err := s.PauseTask("test"); err != nil {
+		t.Fatalf("PauseTask() error = %v", err)
+	}
+	if err := s.RunTask("test"); err != nil {
+		t.Fatalf("RunTask() error = %v", err)
+	}
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("task did not run")
+	}
+
+	// Wait for the run outcome to be recorded
+	deadline := time.Now().Add(time.Second)
+	var status bot.TaskStatus
+	for time.Now().Before(deadline) {
+		status = s.Tasks()[0]
+		if status.LastError != "" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !status.Paused || status.LastError != "boom" || status.LastRun.IsZero() || status.NextRun.IsZero() {
+		t.Errorf("Tasks() got %+v", status)
+	}
+
+	s.RemoveTask("test")
+	if _, ok := s.GetTask("test"); ok {
+		t.Error("GetTask() found removed task")
+	}
+	if err := s.RunTask("test"); err == nil {
+		t.Error("RunTask() on removed task should fail")
+	}
+}
+
+func TestSchedulerStop(t *testing.T) {
+	s := New(logger.New())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	finished := make(chan error, 1)
+	task := &Task{
+		ID:       "slow",
+		Interval: time.Hour,
+		Execute: func(ctx context.Context) error {
+			close(started)
+			<-release
+			finished <- ctx.Err()
+			return nil
+		},
+	}
+	if err := s.ScheduleTask(context.Background(), task); err != nil {
+		t.Fatalf("ScheduleTask() error = %v", err)
+	}
+	if err := s.RunTask("slow"); err != nil {
+		t.Fatalf("RunTask() error = %v", err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); err == nil {
+		t.Error("Stop() should time out while a run is in progress")
+	}
+
+	close(release)
+	if err := s.Stop(context.Background()); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	if err := <-finished; err != nil {
+		t.Errorf("in-flight run was cancelled: %v", err)
+	}
+	if err := s.ScheduleTask(context.Background(), &Task{ID: "late", Interval: time.Hour}); err == nil {
+		t.Error("ScheduleTask() after Stop() should fail")
+	}
+}
+
+func TestReplaceNotificationJobsKeepsState(t *testing.T) {
+	s := New(logger.New())
+	t.Cleanup(func() { s.Stop(context.Background()) })
+	ctx := context.Background()
+	job := func(id string) *Task {
+		return &Task{ID: id, Interval: time.Hour, Execute: func(context.Context) error { return nil }}
+	}
+
+	if err := s.ReplaceNotificationJobs(ctx, []*Task{job("kept"), job("dropped")}); err != nil {
+		t.Fatalf("ReplaceNotificationJobs() error = %v", err)
+	}
+	if err := s.PauseTask("kept"); err != nil {
+		t.Fatalf("PauseTask() error = %v", err)
+	}
+	lastRun := time.Date(2025, 5, 4, 8, 0, 0, 0, time.UTC)
+	old, _ := s.GetTask("kept")
+	old.mu.Lock()
+	old.lastRun, old.lastErr = lastRun, errors.New("boom")
+	old.mu.Unlock()
+
+	if err := s.ReplaceNotificationJobs(ctx, []*Task{job("kept"), job("added")}); err != nil {
+		t.Fatalf("ReplaceNotificationJobs() error = %v", err)
+	}
+	statuses := s.Tasks()
+	if len(statuses) != 2 || statuses[0].ID != "added" || statuses[1].ID != "kept" {
+		t.Fatalf("Tasks() got %+v", statuses)
+	}
+	if added := statuses[0]; added.Paused || !added.LastRun.IsZero() {
+		t.Errorf("new job got %+v", added)
+	}
+	if kept := statuses[1]; !kept.Paused || !kept.LastRun.Equal(lastRun) || kept.LastError != "boom" {
+		t.Errorf("kept job got %+v", kept)
+	}
+}
+
+func TestReplaceNotificationJobsRejectsAlertsID(t *testing.T) {
+	s := New(logger.New())
+	t.Cleanup(func() { s.Stop(context.Background()) })
+	ctx := context.Background()
+	alerts := &Task{ID: config.AlertsTaskID, Interval: time.Hour, Execute: func(context.Context) error { return nil }}
+	if err := s.ScheduleTask(ctx, alerts); err != nil {
+		t.Fatalf("ScheduleTask() error = %v", err)
+	}
+
+	job := &Task{ID: config.AlertsTaskID, Interval: time.Hour, Execute: func(context.Context) error { return nil }}
+	if err := s.ReplaceNotificationJobs(ctx, []*Task{job}); err == nil {
+		t.Fatal("ReplaceNotificationJobs() with the alerts task ID = nil error")
+	}
+	if task, _ := s.GetTask(config.AlertsTaskID); task != alerts {
+		t.Error("alerts task replaced by a notification job")
+	}
+}
@@ -0,0 +1,90 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultDir is where Docker and Kubernetes mount secret files
+const DefaultDir = "/run/secrets"
+
+// ErrNotFound is returned when a provider has no value for a secret
+var ErrNotFound = errors.New("secret not found")
+
+// Provider looks up secrets by their environment variable name, e.g. BOT_TOKEN
+type Provider interface {
+	Lookup(name string) (string, error)
+}
+
+// Env reads secrets from environment variables
+type Env struct{}
+
+func (Env) Lookup(name string) (string, error) {
+	if value := os.Getenv(name); value != "" {
+		return value, nil
+	}
+	return "", ErrNotFound
+}
+
+// FileEnv follows the NAME_FILE convention: the variable holds the path of a file
+// containing the secret
+type FileEnv struct{}
+
+func (FileEnv) Lookup(name string) (string, error) {
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return "", ErrNotFound
+	}
+	return readSecret(path)
+}
+
+// Dir reads secrets from files named after the secret in a directory. Both the exact
+// name (BOT_TOKEN) and its lowercase form (bot_token) are tried.
+type Dir struct {
+	Path string
+}
+
+func (d Dir) Lookup(name string) (string, error) {
+	for _, file := range []string{name, strings.ToLower(name)} {
+		path := filepath.Join(d.Path, file)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		return readSecret(path)
+	}
+	return "", ErrNotFound
+}
+
+// Chain returns the first secret found by its providers
+type Chain []Provider
+
+func (c Chain) Lookup(name string) (string, error) {
+	for _, provider := range c {
+		value, err := provider.Lookup(name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		return value, err
+	}
+	return "", ErrNotFound
+}
+
+// Default checks NAME_FILE first, then the NAME environment variable, then /run/secrets
+func Default() Provider {
+	return Chain{FileEnv{}, Env{}, Dir{Path: DefaultDir}}
+}
+
+func readSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return value, nil
+}
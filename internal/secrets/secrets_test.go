@@ -0,0 +1,92 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChainLookup(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("bot_token", "from-dir\n")
+	write("TRAIN_API_KEY", "key-from-dir")
+	write("EMPTY", "  \n")
+	filePath := write("token.txt", "from-file\n")
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		secret  string
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "Lowercase file in directory",
+			secret: "BOT_TOKEN",
+			want:   "from-dir",
+		},
+		{
+			name:   "Exact file in directory",
+			secret: "TRAIN_API_KEY",
+			want:   "key-from-dir",
+		},
+		{
+			name:   "Environment overrides directory",
+			env:    map[string]string{"BOT_TOKEN": "from-env"},
+			secret: "BOT_TOKEN",
+			want:   "from-env",
+		},
+		{
+			name:   "_FILE overrides environment",
+			env:    map[string]string{"BOT_TOKEN": "from-env", "BOT_TOKEN_FILE": filePath},
+			secret: "BOT_TOKEN",
+			want:   "from-file",
+		},
+		{
+			name:    "Missing _FILE target is an error",
+			env:     map[string]string{"BOT_TOKEN_FILE": filepath.Join(dir, "missing")},
+			secret:  "BOT_TOKEN",
+			wantErr: os.ErrNotExist,
+		},
+		{
+			name:    "Not found",
+			secret:  "LOG_HASH_SALT",
+			wantErr: ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"BOT_TOKEN", "BOT_TOKEN_FILE", "TRAIN_API_KEY", "LOG_HASH_SALT"} {
+				t.Setenv(key, tt.env[key])
+			}
+			provider := Chain{FileEnv{}, Env{}, Dir{Path: dir}}
+
+			got, err := provider.Lookup(tt.secret)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Lookup() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Lookup() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := (Dir{Path: dir}).Lookup("EMPTY"); err == nil {
+		t.Error("Lookup() of an empty secret file should fail")
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"rail-go/internal/logger"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// checkTimeout bounds a single health check
+const checkTimeout = 5 * time.Second
+
+// Check reports the health of a dependency
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Server exposes /metrics, /healthz and /readyz over HTTP
+type Server struct {
+	mu        sync.RWMutex
+	logger    *logger.Logger
+	http      *http.Server
+	mux       *http.ServeMux
+	liveness  []namedCheck
+	readiness []namedCheck
+}
+
+func New(addr string, log *logger.Logger) *Server {
+	mux := http.NewServeMux()
+	s := &Server{
+		logger: log,
+		mux:    mux,
+		http: &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		s.serveChecks(w, r, s.checks(false))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		s.serveChecks(w, r, s.checks(true))
+	})
+
+	return s
+}
+
+// AddLivenessCheck adds a check to /healthz. Liveness checks are also part of /readyz.
+func (s *Server) AddLivenessCheck(name string, check Check) {
+	s.mu.Lock()
+	s.liveness = append(s.liveness, namedCheck{name: name, check: check})
+	s.mu.Unlock()
+}
+
+// AddReadinessCheck adds a check to /readyz
+func (s *Server) AddReadinessCheck(name string, check Check) {
+	s.mu.Lock()
+	s.readiness = append(s.readiness, namedCheck{name: name, check: check})
+	s.mu.Unlock()
+}
+
+// Start serves HTTP until the context is cancelled
+func (s *Server) Start(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		s.logger.Info("http server starting", "addr", s.http.Addr)
+		errCh <- s.http.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.logger.Info("http server shutting down")
+		return s.http.Shutdown(shutdownCtx)
+	}
+}
+
+// Handle registers an additional handler on the server
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// HandleLocal registers a handler that only serves requests made from the host
+// itself, for operator endpoints that change the running bot. Requests relayed by
+// a proxy are refused even when the proxy runs on the same host.
+func (s *Server) HandleLocal(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !fromLoopback(r) {
+			s.logger.Warn("non-local request refused", "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+}
+
+func fromLoopback(r *http.Request) bool {
+	if r.Header.Get("X-Forwarded-For") != "" || r.Header.Get("Forwarded") != "" {
+		return false
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *Server) checks(readiness bool) []namedCheck {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	checks := append([]namedCheck(nil), s.liveness...)
+	if readiness {
+		checks = append(checks, s.readiness...)
+	}
+	return checks
+}
+
+func (s *Server) serveChecks(w http.ResponseWriter, r *http.Request, checks []namedCheck) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	status := http.StatusOK
+	results := make(map[string]string, len(checks))
+	for _, c := range checks {
+		if err := c.check(ctx); err != nil {
+			// The response is public, so the details only go to the log
+			s.logger.Warn("health check failed", "check", c.name, "error", err)
+			status = http.StatusServiceUnavailable
+			results[c.name] = "unavailable"
+			continue
+		}
+		results[c.name] = "ok"
+	}
+
+	body := map[string]any{"status": "ok", "checks": results}
+	if status != http.StatusOK {
+		body["status"] = "unavailable"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		s.logger.Error("failed to write health response", "error", err)
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"rail-go/internal/logger"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	s := New(":0", logger.New())
+	s.AddLivenessCheck("telegram", func(ctx context.Context) error { return nil })
+	s.AddReadinessCheck("rail_api", func(ctx context.Context) error { return errors.New("down") })
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{name: "Liveness", path: "/healthz", want: http.StatusOK},
+		{name: "Readiness", path: "/readyz", want: http.StatusServiceUnavailable},
+		{name: "Metrics", path: "/metrics", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.http.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Errorf("GET %s got %d, want %d", tt.path, rec.Code, tt.want)
+			}
+			if strings.Contains(rec.Body.String(), "down") {
+				t.Errorf("GET %s body %q shows the check error", tt.path, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleLocal(t *testing.T) {
+	s := New(":0", logger.New())
+	s.HandleLocal("/loglevel", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		want      int
+	}{
+		{name: "IPv4 loopback", remote: "127.0.0.1:5000", want: http.StatusOK},
+		{name: "IPv6 loopback", remote: "[::1]:5000", want: http.StatusOK},
+		{name: "Other host", remote: "192.0.2.1:5000", want: http.StatusForbidden},
+		{name: "Local proxy", remote: "127.0.0.1:5000", forwarded: "192.0.2.1", want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/loglevel", nil)
+			req.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			rec := httptest.NewRecorder()
+			s.http.Handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("PUT /loglevel from %s got %d, want %d", tt.remote, rec.Code, tt.want)
+			}
+		})
+	}
+}
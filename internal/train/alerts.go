@@ -0,0 +1,127 @@
+package train
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Alert is a service disruption announcement (planned works, line closures, etc.)
+type Alert struct {
+	ID         string
+	Title      string
+	Content    string
+	Link       string
+	StationIDs []string
+	Start      time.Time
+	End        time.Time
+}
+
+type updatesResponse struct {
+	Result []struct {
+		ID            int    `json:"updateID"`
+		Header        string `json:"updateHeader"`
+		Content       string `json:"updateContent"`
+		Link          string `json:"updateLink"`
+		Stations      []int  `json:"stations"`
+		StartValidity string `json:"startValidity"`
+		EndValidity   string `json:"endValidity"`
+	} `json:"result"`
+}
+
+// GetAlerts returns the announcements that are currently in effect
+func (s *Service) GetAlerts(ctx context.Context) ([]Alert, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.config.Train.UpdatesURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	params := req.URL.Query()
+	params.Set("lang", "he")
+	req.URL.RawQuery = params.Encode()
+
+	var updates updatesResponse
+	if err := s.doJSON(req, "updates", &updates); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	alerts := make([]Alert, 0, len(updates.Result))
+	for _, u := range updates.Result {
+		alert := Alert{
+			ID:      strconv.Itoa(u.ID),
+			Title:   strings.TrimSpace(u.Header),
+			Content: strings.TrimSpace(u.Content),
+			Link:    u.Link,
+			Start:   parseAPITime(u.StartValidity),
+			End:     parseAPITime(u.EndValidity),
+		}
+		for _, id := range u.Stations {
+			alert.StationIDs = append(alert.StationIDs, strconv.Itoa(id))
+		}
+		if !alert.End.IsZero() && alert.End.Before(now) {
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// IsGeneral reports whether the alert is not tied to specific stations
+func (a Alert) IsGeneral() bool {
+	return len(a.StationIDs) == 0
+}
+
+// AffectsRoute reports whether the alert explicitly mentions one of the route stations
+func (a Alert) AffectsRoute(from, to string) bool {
+	for _, id := range a.StationIDs {
+		if id == from || id == to {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatAlert renders a single alert as a Telegram message
+func (s *Service) FormatAlert(alert Alert) string {
+	result := fmt.Sprintf("⚠️ %s\n", alert.Title)
+	if alert.Content != "" {
+		result += alert.Content + "\n"
+	}
+	if !alert.Start.IsZero() && !alert.End.IsZero() {
+		result += fmt.Sprintf("🗓 %s - %s\n",
+			alert.Start.Format("02/01 15:04"), alert.End.Format("02/01 15:04"))
+	}
+	if len(alert.StationIDs) > 0 {
+		names := make([]string, 0, len(alert.StationIDs))
+		for _, id := range alert.StationIDs {
+			names = append(names, s.GetStationName(id))
+		}
+		result += fmt.Sprintf("🚉 %s\n", strings.Join(names, ", "))
+	}
+	if alert.Link != "" {
+		result += alert.Link + "\n"
+	}
+	return result
+}
+
+// FormatAlerts renders a list of alerts and splits it into message chunks
+func (s *Service) FormatAlerts(alerts []Alert) []string {
+	var result string
+	for _, alert := range alerts {
+		result += s.FormatAlert(alert) + "\n"
+	}
+	return splitMessage(result)
+}
+
+func parseAPITime(timeStr string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02T15:04:05", timeStr, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
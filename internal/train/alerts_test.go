@@ -0,0 +1,56 @@
+package train
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"rail-go/internal/logger"
+)
+
+func TestGetAlerts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result": [
+			{"updateID": 1, "updateHeader": "עבודות תשתית", "stations": [3700, 4600], "endValidity": "2999-01-01T00:00:00"},
+			{"updateID": 2, "updateHeader": "הודעה כללית"},
+			{"updateID": 3, "updateHeader": "הודעה ישנה", "endValidity": "2000-01-01T00:00:00"}
+		]}`))
+	}))
+	defer server.Close()
+
+	cfg := NewTestConfig()
+	cfg.Train.UpdatesURL = server.URL
+	service := NewService(cfg, logger.New())
+
+	alerts, err := service.GetAlerts(context.Background())
+	if err != nil {
+		t.Fatalf("GetAlerts() error = %v", err)
+	}
+	if len(alerts) != 2 {
+		t.Fatalf("GetAlerts() got %d alerts, want 2", len(alerts))
+	}
+
+	tests := []struct {
+		name     string
+		alert    Alert
+		from, to string
+		affects  bool
+		general  bool
+	}{
+		{name: "Station on route", alert: alerts[0], from: "4600", to: "8700", affects: true},
+		{name: "Station off route", alert: alerts[0], from: "3500", to: "8700", affects: false},
+		{name: "General alert", alert: alerts[1], from: "4600", to: "8700", affects: false, general: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.alert.AffectsRoute(tt.from, tt.to); got != tt.affects {
+				t.Errorf("AffectsRoute() got %v, want %v", got, tt.affects)
+			}
+			if got := tt.alert.IsGeneral(); got != tt.general {
+				t.Errorf("IsGeneral() got %v, want %v", got, tt.general)
+			}
+		})
+	}
+}
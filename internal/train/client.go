@@ -0,0 +1,74 @@
+package train
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"rail-go/internal/logger"
+	"rail-go/internal/metrics"
+)
+
+// apiHealth remembers the outcome of the last Israel Railways API call
+type apiHealth struct {
+	err error
+	at  time.Time
+}
+
+// doJSON sends an API request and decodes the JSON response into out.
+// The endpoint name is used to label metrics.
+func (s *Service) doJSON(req *http.Request, endpoint string, out any) error {
+	req.Header.Set("User-Agent", s.config.Train.UserAgent)
+	req.Header.Set("ocp-apim-subscription-key", s.config.Train.APIKey)
+
+	start := time.Now()
+	err := s.send(req, endpoint, out)
+	duration := time.Since(start)
+	metrics.RailAPIDuration.WithLabelValues(endpoint).Observe(duration.Seconds())
+
+	log := logger.FromContext(req.Context())
+	if err != nil {
+		log.Error("rail api request failed", "endpoint", endpoint, "duration", duration, "error", err)
+	} else {
+		log.Info("rail api request completed", "endpoint", endpoint, "duration", duration)
+	}
+
+	s.healthMu.Lock()
+	s.health = apiHealth{err: err, at: time.Now()}
+	s.healthMu.Unlock()
+
+	return err
+}
+
+func (s *Service) send(req *http.Request, endpoint string, out any) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		metrics.RailAPIRequests.WithLabelValues(endpoint, "error").Inc()
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	metrics.RailAPIRequests.WithLabelValues(endpoint, strconv.Itoa(resp.StatusCode)).Inc()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// CheckHealth reports an error when the last Israel Railways API call failed
+func (s *Service) CheckHealth() error {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	if s.health.err != nil {
+		return fmt.Errorf("last rail API call at %s failed: %w",
+			s.health.at.Format(time.RFC3339), s.health.err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package train
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Trains stop running in the afternoon before a rest day and resume after it ends
+const (
+	lastTrainHour    = 16
+	lastTrainMinute  = 0
+	resumeHour       = 19
+	resumeMinute     = 30
+	holidayDayLayout = "2006-01-02"
+)
+
+// HOLIDAYS lists the holidays with no train service (the day itself, not the eve).
+// The dates follow the Hebrew calendar and are not computed, so the list has to be
+// extended every year; past its last year only Shabbat is detected.
+var HOLIDAYS = map[string]string{
+	// 2025
+	"2025-04-13": "פסח",
+	"2025-04-19": "שביעי של פסח",
+	"2025-06-02": "שבועות",
+	"2025-09-23": "ראש השנה",
+	"2025-09-24": "ראש השנה",
+	"2025-10-02": "יום כיפור",
+	"2025-10-07": "סוכות",
+	"2025-10-14": "שמחת תורה",
+	// 2026
+	"2026-04-02": "פסח",
+	"2026-04-08": "שביעי של פסח",
+	"2026-05-22": "שבועות",
+	"2026-09-12": "ראש השנה",
+	"2026-09-13": "ראש השנה",
+	"2026-09-21": "יום כיפור",
+	"2026-09-26": "סוכות",
+	"2026-10-03": "שמחת תורה",
+	// 2027
+	"2027-04-22": "פסח",
+	"2027-04-28": "שביעי של פסח",
+	"2027-06-11": "שבועות",
+	"2027-10-02": "ראש השנה",
+	"2027-10-03": "ראש השנה",
+	"2027-10-11": "יום כיפור",
+	"2027-10-16": "סוכות",
+	"2027-10-23": "שמחת תורה",
+}
+
+var hebrewWeekdays = [...]string{"ראשון", "שני", "שלישי", "רביעי", "חמישי", "שישי", "שבת"}
+
+// ServiceWindow is a period with no train service
+type ServiceWindow struct {
+	Start time.Time
+	End   time.Time
+	Name  string
+}
+
+// HolidaysCoverYear reports whether HOLIDAYS lists the holidays of the year
+func HolidaysCoverYear(year int) bool {
+	prefix := fmt.Sprintf("%04d-", year)
+	for day := range HOLIDAYS {
+		if strings.HasPrefix(day, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRestDay reports whether there is no train service during the given day
+func isRestDay(day time.Time) (string, bool) {
+	if name, ok := HOLIDAYS[day.Format(holidayDayLayout)]; ok {
+		return name, true
+	}
+	if day.Weekday() == time.Saturday {
+		return "שבת", true
+	}
+	return "", false
+}
+
+// NoServiceWindow returns the no-service window containing t, if any
+func NoServiceWindow(t time.Time) (ServiceWindow, bool) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	eveCutoff := day.Add(lastTrainHour*time.Hour + lastTrainMinute*time.Minute)
+	resume := day.Add(resumeHour*time.Hour + resumeMinute*time.Minute)
+
+	var restDay time.Time
+	if _, ok := isRestDay(day.AddDate(0, 0, 1)); ok && !t.Before(eveCutoff) {
+		restDay = day.AddDate(0, 0, 1)
+	} else if _, ok := isRestDay(day); ok && t.Before(resume) {
+		restDay = day
+	} else {
+		return ServiceWindow{}, false
+	}
+
+	first, last := restDay, restDay
+	for {
+		if _, ok := isRestDay(first.AddDate(0, 0, -1)); !ok {
+			break
+		}
+		first = first.AddDate(0, 0, -1)
+	}
+	for {
+		if _, ok := isRestDay(last.AddDate(0, 0, 1)); !ok {
+			break
+		}
+		last = last.AddDate(0, 0, 1)
+	}
+
+	name, _ := isRestDay(restDay)
+	eve := first.AddDate(0, 0, -1)
+	return ServiceWindow{
+		Start: eve.Add(lastTrainHour*time.Hour + lastTrainMinute*time.Minute),
+		End:   last.Add(resumeHour*time.Hour + resumeMinute*time.Minute),
+		Name:  name,
+	}, true
+}
+
+// formatNoService explains when the service resumes, ahead of the first trains
+func formatNoService(window ServiceWindow) string {
+	return fmt.Sprintf("🕯 %s\nהרכבות הראשונות לאחר חידוש השירות:\n\n", NoServiceNotice(window))
+}
+
+// NoServiceNotice tells in Hebrew that there is no service during the window and
+// when it resumes
+func NoServiceNotice(window ServiceWindow) string {
+	return fmt.Sprintf("אין שירות רכבות ב%s. אין רכבות עד יום %s %s.",
+		window.Name,
+		hebrewWeekdays[window.End.Weekday()],
+		window.End.Format("15:04"))
+}
@@ -0,0 +1,96 @@
+package train
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNoServiceWindow(t *testing.T) {
+	tests := []struct {
+		name      string
+		at        time.Time
+		closed    bool
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:   "Regular weekday",
+			at:     time.Date(2025, time.March, 4, 10, 0, 0, 0, time.UTC),
+			closed: false,
+		},
+		{
+			name:   "Friday morning",
+			at:     time.Date(2025, time.March, 7, 10, 0, 0, 0, time.UTC),
+			closed: false,
+		},
+		{
+			name:      "Friday evening",
+			at:        time.Date(2025, time.March, 7, 18, 0, 0, 0, time.UTC),
+			closed:    true,
+			wantStart: time.Date(2025, time.March, 7, 16, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2025, time.March, 8, 19, 30, 0, 0, time.UTC),
+		},
+		{
+			name:      "Saturday afternoon",
+			at:        time.Date(2025, time.March, 8, 12, 0, 0, 0, time.UTC),
+			closed:    true,
+			wantStart: time.Date(2025, time.March, 7, 16, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2025, time.March, 8, 19, 30, 0, 0, time.UTC),
+		},
+		{
+			name:   "Saturday night",
+			at:     time.Date(2025, time.March, 8, 21, 0, 0, 0, time.UTC),
+			closed: false,
+		},
+		{
+			name:      "Holiday eve",
+			at:        time.Date(2025, time.October, 1, 17, 0, 0, 0, time.UTC),
+			closed:    true,
+			wantStart: time.Date(2025, time.October, 1, 16, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2025, time.October, 2, 19, 30, 0, 0, time.UTC),
+		},
+		{
+			name:      "Holiday followed by Shabbat",
+			at:        time.Date(2026, time.September, 12, 12, 0, 0, 0, time.UTC),
+			closed:    true,
+			wantStart: time.Date(2026, time.September, 11, 16, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2026, time.September, 13, 19, 30, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, closed := NoServiceWindow(tt.at)
+			if closed != tt.closed {
+				t.Fatalf("NoServiceWindow() closed = %v, want %v", closed, tt.closed)
+			}
+			if !closed {
+				return
+			}
+			if !window.Start.Equal(tt.wantStart) {
+				t.Errorf("NoServiceWindow() start = %v, want %v", window.Start, tt.wantStart)
+			}
+			if !window.End.Equal(tt.wantEnd) {
+				t.Errorf("NoServiceWindow() end = %v, want %v", window.End, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestHolidaysCoverYear(t *testing.T) {
+	tests := []struct {
+		year int
+		want bool
+	}{
+		{year: 2025, want: true},
+		{year: 2027, want: true},
+		{year: 2024, want: false},
+		{year: 2028, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := HolidaysCoverYear(tt.year); got != tt.want {
+			t.Errorf("HolidaysCoverYear(%d) = %v, want %v", tt.year, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package train
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TripRequest is a route and departure time parsed from free text
+type TripRequest struct {
+	// From and To are station IDs, empty while the station is ambiguous
+	From string
+	To   string
+	// FromCandidates and ToCandidates list the stations an ambiguous name may mean
+	FromCandidates []Station
+	ToCandidates   []Station
+	// FromQuery and ToQuery are the words the stations were recognized from
+	FromQuery string
+	ToQuery   string
+	At        time.Time
+}
+
+// Ambiguous reports whether the user has to pick a station before searching
+func (r TripRequest) Ambiguous() bool {
+	return r.From == "" || r.To == ""
+}
+
+var (
+	// routeSeparators split free text into origin and destination when present
+	routeSeparators = []string{"→", "->", "←", " to ", " אל ", " עד ", ","}
+	// Hebrew attaches "from" and "to" to the station name: מהרצליה, לתל אביב
+	fromPrefixes = []string{"מ"}
+	toPrefixes   = []string{"ל"}
+	// leadingWords are dropped before the origin
+	leadingWords = map[string]bool{"from": true, "מ": true, "מתחנת": true}
+
+	clockPattern = regexp.MustCompile(`(?i)(?:^|\s)(?:at\s+|@\s*|בשעה\s+|ב-?)?(\d{1,2}):(\d{2})(?:\s|$)`)
+	hourPattern  = regexp.MustCompile(`(?i)(?:^|\s)(?:at\s+|@\s*|בשעה\s+|ב-?)(\d{1,2})(?:\s|$)`)
+	dayPattern   = regexp.MustCompile(`(?i)(?:^|\s)(מחר|tomorrow|היום|today)(?:\s|$)`)
+)
+
+// ParseTripRequest understands messages like "מהרצליה לתל אביב השלום ב-8" or
+// "herzliya to hashalom 08:30 tomorrow". Without a time the trip starts now. A time
+// that passed more than an hour ago means the next occurrence: a bare morning hour
+// like "ב-8" becomes 20:00, anything else moves to tomorrow. When a station name is
+// ambiguous the request lists the candidates instead of failing.
+func ParseTripRequest(text string, now time.Time) (TripRequest, error) {
+	text, at, err := extractTime(strings.TrimSpace(text), now)
+	if err != nil {
+		return TripRequest{}, err
+	}
+
+	words := strings.Fields(text)
+	for len(words) > 0 && leadingWords[strings.ToLower(words[0])] {
+		words = words[1:]
+	}
+
+	req, ok := parseStations(words)
+	if !ok {
+		return TripRequest{}, fmt.Errorf("%w: no origin and destination found in %q", ErrUnknownStation, text)
+	}
+	req.At = at
+	return req, nil
+}
+
+// ParseRoute finds an origin and a destination station in free text such as
+// "herzliya hashalom" or "herzliya to tel aviv hashalom"
+func ParseRoute(text string) (from, to string, err error) {
+	req, err := ParseTripRequest(text, time.Now())
+	if err != nil {
+		return "", "", err
+	}
+	if req.Ambiguous() {
+		return "", "", fmt.Errorf("%w: %q matches several stations", ErrUnknownStation, text)
+	}
+	return req.From, req.To, nil
+}
+
+// extractTime removes the departure time and day words from text
+func extractTime(text string, now time.Time) (string, time.Time, error) {
+	day := now
+	explicitDay := false
+	if m := dayPattern.FindStringSubmatch(text); m != nil {
+		if word := strings.ToLower(m[1]); word == "מחר" || word == "tomorrow" {
+			day = now.AddDate(0, 0, 1)
+		}
+		explicitDay = true
+		text = dayPattern.ReplaceAllString(text, " ")
+	}
+
+	hour, minute := -1, 0
+	bareHour := false
+	if m := clockPattern.FindStringSubmatch(text); m != nil {
+		hour, _ = strconv.Atoi(m[1])
+		minute, _ = strconv.Atoi(m[2])
+		text = clockPattern.ReplaceAllString(text, " ")
+	} else if m := hourPattern.FindStringSubmatch(text); m != nil {
+		hour, _ = strconv.Atoi(m[1])
+		bareHour = true
+		text = hourPattern.ReplaceAllString(text, " ")
+	}
+
+	if hour < 0 {
+		if explicitDay && day.YearDay() != now.YearDay() {
+			return text, startOfDay(day), nil
+		}
+		return text, now, nil
+	}
+	if hour > 23 || minute > 59 {
+		return "", time.Time{}, fmt.Errorf("invalid time %02d:%02d", hour, minute)
+	}
+
+	year, month, date := day.Date()
+	at := time.Date(year, month, date, hour, minute, 0, 0, now.Location())
+	if !explicitDay && at.Before(now.Add(-time.Hour)) {
+		if bareHour && hour < 12 && at.Add(12*time.Hour).After(now.Add(-time.Hour)) {
+			at = at.Add(12 * time.Hour)
+		} else {
+			at = at.AddDate(0, 0, 1)
+		}
+	}
+	return text, at, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// side is the outcome of resolving one end of a route
+type side struct {
+	query      string
+	station    Station
+	candidates []Station
+	rank       int
+}
+
+func (s side) resolved() bool {
+	return s.station.ID != ""
+}
+
+// parseStations finds the origin and the destination in words. A split where both
+// stations are unique wins; otherwise a split with one unique and one ambiguous
+// station is returned so the user can be asked which one they meant.
+func parseStations(words []string) (TripRequest, bool) {
+	text := strings.Join(words, " ")
+	for _, sep := range routeSeparators {
+		if left, right, ok := strings.Cut(text, sep); ok {
+			from, to := resolveSide(left, fromPrefixes), resolveSide(right, toPrefixes)
+			if (!from.resolved() && from.candidates == nil) || (!to.resolved() && to.candidates == nil) {
+				return TripRequest{}, false
+			}
+			return newTripRequest(from, to), true
+		}
+	}
+
+	var best *TripRequest
+	bestScore := 0
+	for i := 1; i < len(words); i++ {
+		from := resolveSide(strings.Join(words[:i], " "), fromPrefixes)
+		to := resolveSide(strings.Join(words[i:], " "), toPrefixes)
+		if (!from.resolved() && from.candidates == nil) || (!to.resolved() && to.candidates == nil) {
+			continue
+		}
+		if from.resolved() && to.resolved() && from.station.ID == to.station.ID {
+			continue
+		}
+
+		// Every ambiguous side costs more than the worst unique match
+		score := from.rank + to.rank
+		if !from.resolved() {
+			score += 10
+		}
+		if !to.resolved() {
+			score += 10
+		}
+		if best == nil || score < bestScore {
+			req := newTripRequest(from, to)
+			best, bestScore = &req, score
+		}
+	}
+	if best == nil {
+		return TripRequest{}, false
+	}
+	return *best, true
+}
+
+func newTripRequest(from, to side) TripRequest {
+	return TripRequest{
+		From:           from.station.ID,
+		To:             to.station.ID,
+		FromCandidates: from.candidates,
+		ToCandidates:   to.candidates,
+		FromQuery:      from.query,
+		ToQuery:        to.query,
+	}
+}
+
+// resolveSide resolves a station name, also trying it without a Hebrew prefix.
+// The best ranked reading wins and a unique station beats an ambiguous one.
+func resolveSide(query string, prefixes []string) side {
+	query = strings.TrimSpace(query)
+	variants := []string{query}
+	for _, prefix := range prefixes {
+		if rest, ok := strings.CutPrefix(query, prefix); ok && strings.TrimSpace(rest) != "" {
+			variants = append(variants, strings.TrimSpace(rest))
+		}
+	}
+
+	var result side
+	found := false
+	for _, variant := range variants {
+		matches := bestMatches(variant)
+		if len(matches) == 0 {
+			continue
+		}
+		candidate := side{query: variant, rank: matches[0].rank}
+		if len(matches) == 1 {
+			candidate.station = matches[0]
+		} else {
+			candidate.candidates = matches
+		}
+
+		better := !found || candidate.rank < result.rank ||
+			(candidate.rank == result.rank && candidate.resolved() && !result.resolved())
+		if better {
+			result, found = candidate, true
+		}
+	}
+	return result
+}
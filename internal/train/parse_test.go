@@ -0,0 +1,96 @@
+package train
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTripRequest(t *testing.T) {
+	// Sunday 10:00
+	now := time.Date(2025, 5, 4, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		text           string
+		from, to       string
+		at             time.Time
+		fromCandidates int
+		toCandidates   int
+		wantErr        bool
+	}{
+		{
+			name: "Hebrew with prefixes and hour",
+			text: "מהרצליה לתל אביב השלום ב-8",
+			from: "3500", to: "4600",
+			at: time.Date(2025, 5, 4, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "English with separator and clock time",
+			text: "herzliya to hashalom 08:30",
+			from: "3500", to: "4600",
+			at: time.Date(2025, 5, 5, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "Later today",
+			text: "herzliya hashalom at 17:45",
+			from: "3500", to: "4600",
+			at: time.Date(2025, 5, 4, 17, 45, 0, 0, time.UTC),
+		},
+		{
+			name: "Tomorrow",
+			text: "from kfar sava to herzliya tomorrow 7:15",
+			from: "8700", to: "3500",
+			at: time.Date(2025, 5, 5, 7, 15, 0, 0, time.UTC),
+		},
+		{
+			name: "Station starting with a prefix letter",
+			text: "מלוד למודיעין מרכז",
+			from: "5000", to: "400",
+			at: now,
+		},
+		{
+			name: "No time means now",
+			text: "הרצליה השלום",
+			from: "3500", to: "4600",
+			at: now,
+		},
+		{
+			name: "Ambiguous destination",
+			text: "herzliya to tel aviv",
+			from: "3500",
+			at:   now, toCandidates: 4,
+		},
+		{
+			name:    "Not a route",
+			text:    "מה שלומך",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid time",
+			text:    "herzliya hashalom 25:00",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTripRequest(tt.text, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTripRequest(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.From != tt.from || got.To != tt.to {
+				t.Errorf("route = %s → %s, want %s → %s", got.From, got.To, tt.from, tt.to)
+			}
+			if !got.At.Equal(tt.at) {
+				t.Errorf("At = %v, want %v", got.At, tt.at)
+			}
+			if len(got.FromCandidates) != tt.fromCandidates || len(got.ToCandidates) != tt.toCandidates {
+				t.Errorf("candidates = %d, %d, want %d, %d",
+					len(got.FromCandidates), len(got.ToCandidates), tt.fromCandidates, tt.toCandidates)
+			}
+		})
+	}
+}
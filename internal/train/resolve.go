@@ -0,0 +1,204 @@
+package train
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ErrUnknownStation is returned when a station query matches no single station
+var ErrUnknownStation = errors.New("unknown station")
+
+// Station is a station with its display names
+type Station struct {
+	ID   string `json:"id"`
+	Heb  string `json:"name_he"`
+	Eng  string `json:"name_en"`
+	rank int
+}
+
+// Match ranks, best first
+const (
+	matchExact = iota
+	matchPrefix
+	matchContains
+	matchFuzzy
+)
+
+// FindStations returns the stations whose Hebrew or English name matches the query,
+// best matches first. Besides exact, prefix and substring matches it tolerates small
+// spelling differences, so "herzliya" finds "Hertsliya".
+func FindStations(query string) []Station {
+	q := normalizeName(query)
+	if q == "" {
+		return nil
+	}
+
+	stationsMu.RLock()
+	defer stationsMu.RUnlock()
+
+	if station, ok := STATIONS[strings.TrimSpace(query)]; ok {
+		return []Station{{ID: strings.TrimSpace(query), Heb: station["Heb"], Eng: station["Eng"]}}
+	}
+
+	var matches []Station
+	for id, station := range STATIONS {
+		rank := -1
+		for _, name := range []string{station["Heb"], station["Eng"]} {
+			if r, ok := matchName(q, normalizeName(name)); ok && (rank < 0 || r < rank) {
+				rank = r
+			}
+		}
+		if rank >= 0 {
+			matches = append(matches, Station{ID: id, Heb: station["Heb"], Eng: station["Eng"], rank: rank})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank < matches[j].rank
+		}
+		return matches[i].Eng < matches[j].Eng
+	})
+	return matches
+}
+
+// ResolveStation turns a station ID or name into a station ID. It fails when
+// nothing matches or when several stations match equally well.
+func ResolveStation(query string) (string, error) {
+	station, _, err := resolveRanked(query)
+	return station.ID, err
+}
+
+// resolveRanked resolves a station query and reports how well it matched
+func resolveRanked(query string) (Station, int, error) {
+	best := bestMatches(query)
+	switch len(best) {
+	case 0:
+		return Station{}, 0, fmt.Errorf("%w: no station matches %q", ErrUnknownStation, query)
+	case 1:
+		return best[0], best[0].rank, nil
+	}
+
+	names := make([]string, len(best))
+	for i, m := range best {
+		names[i] = fmt.Sprintf("%s (%s)", m.Eng, m.ID)
+	}
+	return Station{}, 0, fmt.Errorf("%w: %q matches several stations: %s", ErrUnknownStation, query, strings.Join(names, ", "))
+}
+
+// bestMatches returns the stations sharing the best rank for the query. An exact
+// match always wins on its own.
+func bestMatches(query string) []Station {
+	matches := FindStations(query)
+	if len(matches) == 0 {
+		return nil
+	}
+	if matches[0].rank == matchExact {
+		return matches[:1]
+	}
+
+	best := matches[:1]
+	for _, m := range matches[1:] {
+		if m.rank != matches[0].rank {
+			break
+		}
+		best = append(best, m)
+	}
+	return best
+}
+
+// StationByID returns the station with the given ID
+func StationByID(id string) (Station, bool) {
+	stationsMu.RLock()
+	defer stationsMu.RUnlock()
+
+	station, ok := STATIONS[id]
+	if !ok {
+		return Station{}, false
+	}
+	return Station{ID: id, Heb: station["Heb"], Eng: station["Eng"]}, true
+}
+
+// AllStations returns every station, sorted by English name
+func AllStations() []Station {
+	stationsMu.RLock()
+	defer stationsMu.RUnlock()
+
+	stations := make([]Station, 0, len(STATIONS))
+	for id, station := range STATIONS {
+		stations = append(stations, Station{ID: id, Heb: station["Heb"], Eng: station["Eng"]})
+	}
+	sort.Slice(stations, func(i, j int) bool {
+		return stations[i].Eng < stations[j].Eng
+	})
+	return stations
+}
+
+func matchName(query, name string) (int, bool) {
+	switch {
+	case name == query:
+		return matchExact, true
+	case strings.HasPrefix(name, query):
+		return matchPrefix, true
+	case strings.Contains(name, query):
+		return matchContains, true
+	}
+
+	// Compare the query with every run of as many words in the name
+	maxDistance := len([]rune(query)) / 4
+	if maxDistance == 0 {
+		return 0, false
+	}
+	queryWords := len(strings.Fields(query))
+	words := strings.Fields(name)
+	for i := 0; i+queryWords <= len(words); i++ {
+		candidate := strings.Join(words[i:i+queryWords], " ")
+		if levenshtein(query, candidate) <= maxDistance {
+			return matchFuzzy, true
+		}
+	}
+	return 0, false
+}
+
+// normalizeName lowercases a station name and reduces punctuation to single spaces
+func normalizeName(name string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && b.Len() > 0 {
+				b.WriteRune(' ')
+			}
+			b.WriteRune(r)
+			space = false
+			continue
+		}
+		space = true
+	}
+	return b.String()
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,63 @@
+package train
+
+import "testing"
+
+func TestResolveStation(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{name: "Station ID", query: "4600", want: "4600"},
+		{name: "Exact English name", query: "tel aviv hashalom", want: "4600"},
+		{name: "Exact Hebrew name", query: "תל אביב - השלום", want: "4600"},
+		{name: "Punctuation ignored", query: "Tel-Aviv HaShalom", want: "4600"},
+		{name: "Misspelled", query: "herzliya", want: "3500"},
+		{name: "Unique prefix", query: "kfar sava", want: "8700"},
+		{name: "Ambiguous", query: "tel aviv", wantErr: true},
+		{name: "No match", query: "springfield", wantErr: true},
+		{name: "Empty", query: "  ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveStation(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveStation(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveStation(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRoute(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		from, to string
+		wantErr  bool
+	}{
+		{name: "Two short names", text: "herzliya hashalom", from: "3500", to: "4600"},
+		{name: "Multi-word names", text: "kfar sava tel aviv hashalom", from: "8700", to: "4600"},
+		{name: "Separator", text: "tel aviv hashalom to herzliya", from: "4600", to: "3500"},
+		{name: "Arrow", text: "4600 → 3500", from: "4600", to: "3500"},
+		{name: "Hebrew", text: "הרצליה השלום", from: "3500", to: "4600"},
+		{name: "Single station", text: "herzliya", wantErr: true},
+		{name: "Unknown", text: "springfield shelbyville", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := ParseRoute(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRoute(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			}
+			if from != tt.from || to != tt.to {
+				t.Errorf("ParseRoute(%q) = %s, %s, want %s, %s", tt.text, from, to, tt.from, tt.to)
+			}
+		})
+	}
+}
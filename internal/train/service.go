@@ -2,23 +2,35 @@ package train
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"rail-go/internal/config"
 	"rail-go/internal/logger"
+	"rail-go/internal/metrics"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("rail-go/internal/train")
+
 type Service struct {
 	config *config.Config
 	logger *logger.Logger
 	cache  *sync.Map
-	client *http.Client
+	// cachePruned is when expired cache entries were last dropped
+	cachePruned atomic.Int64
+	client      *http.Client
+	healthMu    sync.Mutex
+	health      apiHealth
 }
 
 type ScheduleResponse struct {
@@ -49,66 +61,111 @@ func NewService(cfg *config.Config, log *logger.Logger) *Service {
 	}
 }
 
+// ScheduleMessage is a schedule formatted as chat messages
+type ScheduleMessage struct {
+	// Chunks are the parts of the message, each within Telegram's length limit
+	Chunks []string
+	// From is when the search started: the requested time, or the end of the
+	// Shabbat or holiday it fell on
+	From time.Time
+	// LastDeparture is the departure of the last train shown; zero when no train was found
+	LastDeparture time.Time
+}
+
 func (s *Service) GetSchedule(ctx context.Context, from, to string) ([]string, error) {
+	schedule, err := s.GetScheduleAt(ctx, from, to, time.Now())
+	return schedule.Chunks, err
+}
+
+// GetScheduleAt returns the trains departing after the given time. When the time falls
+// on Shabbat or a holiday, the first trains after the service resumes are returned.
+func (s *Service) GetScheduleAt(ctx context.Context, from, to string, at time.Time) (ScheduleMessage, error) {
+	ctx, span := tracer.Start(ctx, "GetSchedule", trace.WithAttributes(
+		attribute.String("train.from", from),
+		attribute.String("train.to", to)))
+	defer span.End()
+
+	var notice string
+	if window, closed := NoServiceWindow(at); closed {
+		notice = formatNoService(window)
+		at = window.End
+	}
+
 	// Check cache first
-	cacheKey := fmt.Sprintf("%s-%s", from, to)
-	if cached, ok := s.cache.Load(cacheKey); ok {
-		return cached.([]string), nil
+	cacheKey := fmt.Sprintf("%s-%s-%s", from, to, at.Truncate(10*time.Minute).Format("2006-01-02T15:04"))
+	if cached, ok := s.cached(cacheKey, time.Now()); ok {
+		metrics.CacheRequests.WithLabelValues("hit").Inc()
+		span.SetAttributes(attribute.Bool("cache.hit", true))
+		return cached.(ScheduleMessage), nil
+	}
+	metrics.CacheRequests.WithLabelValues("miss").Inc()
+	span.SetAttributes(attribute.Bool("cache.hit", false))
+
+	response, err := s.fetchSchedule(ctx, from, to, at)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return ScheduleMessage{}, err
+	}
+
+	// Format response and split into chunks
+	text, lastDeparture := s.formatSchedule(response, at.Location())
+	schedule := ScheduleMessage{
+		Chunks:        splitMessage(notice + text),
+		From:          at,
+		LastDeparture: lastDeparture,
 	}
 
+	// Cache result
+	s.storeCached(cacheKey, schedule, time.Now())
+
+	return schedule, nil
+}
+
+func (s *Service) fetchSchedule(ctx context.Context, from, to string, at time.Time) (ScheduleResponse, error) {
+	var schedule ScheduleResponse
+
 	// Build request
 	params := url.Values{}
 	params.Add("fromStation", from)
 	params.Add("toStation", to)
-	params.Add("date", time.Now().Format("2006-01-02"))
-	params.Add("hour", time.Now().Format("15:04:05"))
+	params.Add("date", at.Format("2006-01-02"))
+	params.Add("hour", at.Format("15:04:05"))
 	params.Add("scheduleType", "2")
 	params.Add("systemType", "1")
 	params.Add("languageId", "Hebrew")
 
 	// Make request
 	req, err := http.NewRequestWithContext(ctx, "GET",
-		"https://israelrail.azurefd.net/rjpa-prod/api/v1/timetable/searchTrainLuzForDateTime", nil)
+		s.config.Train.BaseURL+"/timetable/searchTrainLuzForDateTime", nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return schedule, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.URL.RawQuery = params.Encode()
-	req.Header.Set("User-Agent", s.config.Train.UserAgent)
-	req.Header.Set("ocp-apim-subscription-key", s.config.Train.APIKey)
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
+	err = s.doJSON(req, "timetable", &schedule)
+	return schedule, err
+}
 
-	var schedule ScheduleResponse
-	if err := json.NewDecoder(resp.Body).Decode(&schedule); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+// formatSchedule writes up to five trains of the response and returns the
+// departure of the last travel shown
+func (s *Service) formatSchedule(schedule ScheduleResponse, loc *time.Location) (string, time.Time) {
+	if len(schedule.Result.Travels) == 0 {
+		return "🚫 לא נמצאו רכבות במסלול זה בשעות הקרובות.\n", time.Time{}
 	}
 
-	// Format response and split into chunks
-	result := s.formatSchedule(schedule)
-	chunks := splitMessage(result)
-
-	// Cache result
-	s.cache.Store(cacheKey, chunks)
-
-	return chunks, nil
-}
-
-func (s *Service) formatSchedule(schedule ScheduleResponse) string {
 	var result string
+	var lastDeparture time.Time
 	count := 0
 	for i, travel := range schedule.Result.Travels {
 		if count >= 5 {
 			break
 		}
+		if len(travel.Trains) > 0 {
+			if departure, err := time.ParseInLocation(apiTimeLayout, travel.Trains[0].DepartureTime, loc); err == nil {
+				lastDeparture = departure
+			}
+		}
 		result += fmt.Sprintf("🚆 %d:\n", i+1)
 		for j, train := range travel.Trains {
 			if count >= 5 {
@@ -127,10 +184,13 @@ func (s *Service) formatSchedule(schedule ScheduleResponse) string {
 		}
 		result += "\n"
 	}
-	return result
+	return result, lastDeparture
 }
 
 func (s *Service) GetStationName(stationID string) string {
+	stationsMu.RLock()
+	defer stationsMu.RUnlock()
+
 	if station, ok := STATIONS[stationID]; ok {
 		return station["Heb"]
 	}
@@ -141,6 +201,9 @@ func (s *Service) GetStationSuggestions(query string) map[string]string {
 	suggestions := make(map[string]string)
 	query = strings.ToLower(query)
 
+	stationsMu.RLock()
+	defer stationsMu.RUnlock()
+
 	for id, station := range STATIONS {
 		// Check Hebrew name
 		if strings.Contains(strings.ToLower(station["Heb"]), query) {
@@ -155,6 +218,68 @@ func (s *Service) GetStationSuggestions(query string) map[string]string {
 	return suggestions
 }
 
+// scheduleCacheTTL is how long a fetched schedule is served from the cache.
+// Cache keys hold the 10-minute slot of the requested time, so without expiry
+// every slot ever asked for would stay in memory.
+const scheduleCacheTTL = 10 * time.Minute
+
+type cacheEntry struct {
+	value   any
+	expires time.Time
+}
+
+// cached returns the value stored under key unless it has expired
+func (s *Service) cached(key string, now time.Time) (any, bool) {
+	v, ok := s.cache.Load(key)
+	if !ok {
+		return nil, false
+	}
+	entry := v.(*cacheEntry)
+	if !now.Before(entry.expires) {
+		s.cache.CompareAndDelete(key, v)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// storeCached caches value under key and, at most once per TTL, drops the
+// expired entries
+func (s *Service) storeCached(key string, value any, now time.Time) {
+	s.cache.Store(key, &cacheEntry{value: value, expires: now.Add(scheduleCacheTTL)})
+
+	last := s.cachePruned.Load()
+	if now.Sub(time.Unix(0, last)) < scheduleCacheTTL || !s.cachePruned.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	s.cache.Range(func(key, v any) bool {
+		if !now.Before(v.(*cacheEntry).expires) {
+			s.cache.CompareAndDelete(key, v)
+		}
+		return true
+	})
+}
+
+// FlushCache drops all cached schedules and returns how many entries were removed
+func (s *Service) FlushCache() int {
+	count := 0
+	s.cache.Range(func(key, _ any) bool {
+		s.cache.Delete(key)
+		count++
+		return true
+	})
+	return count
+}
+
+// CacheSize returns the number of cached schedules
+func (s *Service) CacheSize() int {
+	count := 0
+	s.cache.Range(func(_, _ any) bool {
+		count++
+		return true
+	})
+	return count
+}
+
 func (s *Service) formatTime(timeStr string) string {
 	t, err := time.Parse("2006-01-02T15:04:05", timeStr)
 	if err != nil {
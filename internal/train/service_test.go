@@ -2,6 +2,7 @@ package train
 
 import (
 	"testing"
+	"time"
 
 	"rail-go/internal/logger"
 )
@@ -155,3 +156,22 @@ func TestSplitMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestScheduleCacheExpiry(t *testing.T) {
+	service := NewService(NewTestConfig(), logger.New())
+	start := time.Date(2025, 5, 4, 8, 0, 0, 0, time.UTC)
+
+	service.storeCached("old", []string{"08:00"}, start)
+	if _, ok := service.cached("old", start.Add(scheduleCacheTTL-time.Second)); !ok {
+		t.Error("cached() before the TTL = miss, want hit")
+	}
+	if _, ok := service.cached("old", start.Add(scheduleCacheTTL)); ok {
+		t.Error("cached() after the TTL = hit, want miss")
+	}
+
+	service.storeCached("stale", []string{"08:10"}, start)
+	service.storeCached("new", []string{"09:00"}, start.Add(2*scheduleCacheTTL))
+	if got := service.CacheSize(); got != 1 {
+		t.Errorf("CacheSize() after storing past the TTL = %d, want 1", got)
+	}
+}
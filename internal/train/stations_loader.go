@@ -0,0 +1,47 @@
+package train
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// stationsMu guards STATIONS against reloads while it is being read
+var stationsMu sync.RWMutex
+
+// LoadStations replaces the built-in station list with the one in the given JSON file.
+// The file uses the same layout as STATIONS: {"3700": {"Heb": "...", "Eng": "..."}}.
+func LoadStations(path string) (int, error) {
+	stations, err := ReadStations(path)
+	if err != nil {
+		return 0, err
+	}
+	SetStations(stations)
+	return len(stations), nil
+}
+
+// ReadStations reads a stations file without using it, so that a reload can check
+// everything before changing anything
+func ReadStations(path string) (map[string]map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stations file: %w", err)
+	}
+
+	var stations map[string]map[string]string
+	if err := json.Unmarshal(data, &stations); err != nil {
+		return nil, fmt.Errorf("failed to decode stations file: %w", err)
+	}
+	if len(stations) == 0 {
+		return nil, fmt.Errorf("stations file %s has no stations", path)
+	}
+	return stations, nil
+}
+
+// SetStations replaces the station list
+func SetStations(stations map[string]map[string]string) {
+	stationsMu.Lock()
+	STATIONS = stations
+	stationsMu.Unlock()
+}
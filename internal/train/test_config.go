@@ -14,6 +14,18 @@ func NewTestConfig() *config.Config {
 	cfg.Bot.Token = "test_token"
 	cfg.Bot.Debug = true
 	cfg.Bot.Timeout = 60
+	cfg.Bot.ShutdownTimeout = 15 * time.Second
+
+	// Log configuration
+	cfg.Log.Level = "info"
+	cfg.Log.Encoding = "json"
+	cfg.Log.Outputs = []string{"stdout"}
+
+	// Rate limit configuration
+	cfg.RateLimit.UserPerMinute = 20
+	cfg.RateLimit.UserBurst = 5
+	cfg.RateLimit.GlobalPerMinute = 600
+	cfg.RateLimit.GlobalBurst = 30
 
 	// Scheduler configuration
 	cfg.Scheduler.DefaultInterval = time.Hour * 24 * 30 // Default to monthly
@@ -25,11 +37,13 @@ func NewTestConfig() *config.Config {
 	cfg.Notifications.Templates = map[string]string{
 		"monthly": "need to use sibus",
 	}
+	cfg.Notifications.AlertsInterval = 15 * time.Minute
 
 	// Train service configuration
 	cfg.Train.APIKey = "test_api_key"
 	cfg.Train.UserAgent = "test_user_agent"
 	cfg.Train.BaseURL = "https://test.api.rail.co.il"
+	cfg.Train.UpdatesURL = "https://test.api.rail.co.il/updates"
 	cfg.Train.Timeout = 30 * time.Second
 
 	return cfg
@@ -1,8 +0,0 @@
-package train
-
-const (
-	API_KEY = "4b0d355121fe4e0bb3d86e902efe9f20"
-
-	USER_AGENT = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15"
-	API_BASE   = "https://israelrail.azurefd.net/rjpa-prod/api/v1"
-)
@@ -0,0 +1,173 @@
+package train
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"rail-go/internal/metrics"
+)
+
+// apiTimeLayout is the local time format of the timetable API
+const apiTimeLayout = "2006-01-02T15:04:05"
+
+// Leg is a single train ride of a trip
+type Leg struct {
+	From         string    `json:"from"`
+	FromName     string    `json:"from_name"`
+	FromPlatform int       `json:"from_platform"`
+	To           string    `json:"to"`
+	ToName       string    `json:"to_name"`
+	ToPlatform   int       `json:"to_platform"`
+	Departure    time.Time `json:"departure"`
+	Arrival      time.Time `json:"arrival"`
+}
+
+// Trip is a way to get from one station to another, possibly with changes
+type Trip struct {
+	Departure time.Time `json:"departure"`
+	Arrival   time.Time `json:"arrival"`
+	Legs      []Leg     `json:"legs"`
+}
+
+// Changes returns the number of train changes of the trip
+func (t Trip) Changes() int {
+	if len(t.Legs) == 0 {
+		return 0
+	}
+	return len(t.Legs) - 1
+}
+
+// Schedule is the answer to a trip query between two stations
+type Schedule struct {
+	From Station   `json:"from"`
+	To   Station   `json:"to"`
+	At   time.Time `json:"at"`
+	// Notice explains why trips start later than requested, e.g. on Shabbat
+	Notice string `json:"notice,omitempty"`
+	// NoService is the no-service window Notice is about, for callers that word
+	// it themselves, like the Hebrew chat messages
+	NoService *ServiceWindow `json:"-"`
+	Trips     []Trip         `json:"trips"`
+}
+
+// PlanTrips resolves the station queries (IDs or names) and returns the trips
+// departing around the given time, skipping over Shabbat and holidays.
+// Station errors wrap ErrUnknownStation.
+func (s *Service) PlanTrips(ctx context.Context, fromQuery, toQuery string, at time.Time) (Schedule, error) {
+	from, err := resolveStation(fromQuery)
+	if err != nil {
+		return Schedule{}, err
+	}
+	to, err := resolveStation(toQuery)
+	if err != nil {
+		return Schedule{}, err
+	}
+	result := Schedule{From: from, To: to, At: at}
+
+	if window, closed := NoServiceWindow(at); closed {
+		result.Notice = fmt.Sprintf("no service during %s, showing trains from %s",
+			window.Name, window.End.Format("Mon 2006-01-02 15:04"))
+		result.NoService = &window
+		at = window.End
+	}
+
+	trips, err := s.GetTrips(ctx, result.From.ID, result.To.ID, at)
+	if err != nil {
+		return Schedule{}, err
+	}
+	result.Trips = trips
+	return result, nil
+}
+
+func resolveStation(query string) (Station, error) {
+	id, err := ResolveStation(query)
+	if err != nil {
+		return Station{}, err
+	}
+	station, _ := StationByID(id)
+	return station, nil
+}
+
+// GetTrips returns the trips between two stations departing around the given time,
+// as structured data rather than a chat message. Results are cached like GetSchedule.
+func (s *Service) GetTrips(ctx context.Context, from, to string, at time.Time) ([]Trip, error) {
+	cacheKey := fmt.Sprintf("trips-%s-%s-%s", from, to, at.Truncate(10*time.Minute).Format("2006-01-02T15:04"))
+	if cached, ok := s.cached(cacheKey, time.Now()); ok {
+		metrics.CacheRequests.WithLabelValues("hit").Inc()
+		return cached.([]Trip), nil
+	}
+	metrics.CacheRequests.WithLabelValues("miss").Inc()
+
+	schedule, err := s.fetchSchedule(ctx, from, to, at)
+	if err != nil {
+		return nil, err
+	}
+
+	trips := make([]Trip, 0, len(schedule.Result.Travels))
+	for _, travel := range schedule.Result.Travels {
+		if len(travel.Trains) == 0 {
+			continue
+		}
+
+		var trip Trip
+		for _, part := range travel.Trains {
+			leg, err := s.newLeg(part, at.Location())
+			if err != nil {
+				return nil, err
+			}
+			trip.Legs = append(trip.Legs, leg)
+		}
+		trip.Departure = trip.Legs[0].Departure
+		trip.Arrival = trip.Legs[len(trip.Legs)-1].Arrival
+		trips = append(trips, trip)
+	}
+
+	s.storeCached(cacheKey, trips, time.Now())
+	return trips, nil
+}
+
+// ParseTime parses a departure time relative to now: HH:MM (today),
+// "YYYY-MM-DD HH:MM" or RFC 3339. An empty value means now.
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return now, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.ParseInLocation("15:04", value, now.Location()); err == nil {
+		year, month, day := now.Date()
+		return time.Date(year, month, day, t.Hour(), t.Minute(), 0, 0, now.Location()), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use HH:MM, \"YYYY-MM-DD HH:MM\" or RFC 3339", value)
+}
+
+func (s *Service) newLeg(part TrainRoutePart, loc *time.Location) (Leg, error) {
+	departure, err := time.ParseInLocation(apiTimeLayout, part.DepartureTime, loc)
+	if err != nil {
+		return Leg{}, fmt.Errorf("invalid departure time %q: %w", part.DepartureTime, err)
+	}
+	arrival, err := time.ParseInLocation(apiTimeLayout, part.ArrivalTime, loc)
+	if err != nil {
+		return Leg{}, fmt.Errorf("invalid arrival time %q: %w", part.ArrivalTime, err)
+	}
+
+	from := fmt.Sprintf("%d", part.OriginStation)
+	to := fmt.Sprintf("%d", part.DestinationStation)
+	return Leg{
+		From:         from,
+		FromName:     s.GetStationName(from),
+		FromPlatform: part.OriginPlatform,
+		To:           to,
+		ToName:       s.GetStationName(to),
+		ToPlatform:   part.DestPlatform,
+		Departure:    departure,
+		Arrival:      arrival,
+	}, nil
+}
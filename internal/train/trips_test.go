@@ -0,0 +1,100 @@
+package train
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"rail-go/internal/logger"
+)
+
+func TestGetTrips(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("fromStation"); got != "3500" {
+			t.Errorf("fromStation = %q, want 3500", got)
+		}
+		w.Write([]byte(`{"result": {"travels": [
+			{"trains": [{"orignStation": 3500, "destinationStation": 4600, "departureTime": "2025-05-04T08:05:00", "arrivalTime": "2025-05-04T08:25:00", "originPlatform": 1, "destPlatform": 2}]},
+			{"trains": [
+				{"orignStation": 3500, "destinationStation": 3700, "departureTime": "2025-05-04T08:20:00", "arrivalTime": "2025-05-04T08:35:00", "originPlatform": 2, "destPlatform": 3},
+				{"orignStation": 3700, "destinationStation": 4600, "departureTime": "2025-05-04T08:42:00", "arrivalTime": "2025-05-04T08:47:00", "originPlatform": 4, "destPlatform": 1}
+			]},
+			{"trains": []}
+		]}}`))
+	}))
+	defer server.Close()
+
+	cfg := NewTestConfig()
+	cfg.Train.BaseURL = server.URL
+	service := NewService(cfg, logger.New())
+
+	at := time.Date(2025, 5, 4, 8, 0, 0, 0, time.UTC)
+	trips, err := service.GetTrips(context.Background(), "3500", "4600", at)
+	if err != nil {
+		t.Fatalf("GetTrips() error = %v", err)
+	}
+	if len(trips) != 2 {
+		t.Fatalf("GetTrips() got %d trips, want 2", len(trips))
+	}
+
+	tests := []struct {
+		name      string
+		trip      Trip
+		departure string
+		arrival   string
+		changes   int
+	}{
+		{name: "Direct", trip: trips[0], departure: "08:05", arrival: "08:25", changes: 0},
+		{name: "One change", trip: trips[1], departure: "08:20", arrival: "08:47", changes: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.trip.Departure.Format("15:04"); got != tt.departure {
+				t.Errorf("Departure = %s, want %s", got, tt.departure)
+			}
+			if got := tt.trip.Arrival.Format("15:04"); got != tt.arrival {
+				t.Errorf("Arrival = %s, want %s", got, tt.arrival)
+			}
+			if got := tt.trip.Changes(); got != tt.changes {
+				t.Errorf("Changes() = %d, want %d", got, tt.changes)
+			}
+		})
+	}
+
+	if got := trips[1].Legs[1].FromName; got != "תל אביב - סבידור מרכז" {
+		t.Errorf("FromName = %q", got)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	loc := time.FixedZone("IST", 3*60*60)
+	now := time.Date(2025, 5, 4, 10, 30, 0, 0, loc)
+
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "Empty is now", value: "", want: now},
+		{name: "Time of day", value: "08:00", want: time.Date(2025, 5, 4, 8, 0, 0, 0, loc)},
+		{name: "Date and time", value: "2025-05-06 17:45", want: time.Date(2025, 5, 6, 17, 45, 0, 0, loc)},
+		{name: "Date and time with T", value: "2025-05-06T17:45", want: time.Date(2025, 5, 6, 17, 45, 0, 0, loc)},
+		{name: "RFC 3339", value: "2025-05-06T17:45:00+03:00", want: time.Date(2025, 5, 6, 17, 45, 0, 0, loc)},
+		{name: "Invalid", value: "tomorrow", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTime(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTime(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTime(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}